@@ -0,0 +1,159 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+)
+
+// listCommentLimit 获取评论列表时单次请求的评论数量上限
+const listCommentLimit = 50
+
+// OpenComment 打开已群发文章的评论
+// 评论管理接口通过群发或发布返回的msg_data_id及文章在图文消息中的位置index指定文章，第一篇index为0
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) OpenComment(msgDataID int64, index int) error {
+	data := map[string]interface{}{
+		"msg_data_id": msgDataID,
+		"index":       index,
+	}
+	return s.postComment("open", data, ErrOpenComment)
+}
+
+// CloseComment 关闭已群发文章的评论
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) CloseComment(msgDataID int64, index int) error {
+	data := map[string]interface{}{
+		"msg_data_id": msgDataID,
+		"index":       index,
+	}
+	return s.postComment("close", data, ErrCloseComment)
+}
+
+// ListComment 查看指定文章的评论数据，begin从0开始，count取值在1到50之间，commentType为 CommentTypeAll 等筛选类型
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) ListComment(msgDataID int64, index, begin, count, commentType int) (*ListCommentResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"msg_data_id": msgDataID,
+		"index":       index,
+		"begin":       begin,
+		"count":       count,
+		"type":        commentType,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/comment/list?access_token=%s", s.AccessToken)
+
+	var responseJson ListCommentResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrListComment, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// MarkElectComment 将评论标记精选
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) MarkElectComment(msgDataID int64, index int, userCommentID int64) error {
+	return s.postComment("markelect", commentData(msgDataID, index, userCommentID), ErrMarkElectComment)
+}
+
+// UnmarkElectComment 将评论取消精选
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) UnmarkElectComment(msgDataID int64, index int, userCommentID int64) error {
+	return s.postComment("unmarkelect", commentData(msgDataID, index, userCommentID), ErrUnmarkElectComment)
+}
+
+// DeleteComment 删除评论
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) DeleteComment(msgDataID int64, index int, userCommentID int64) error {
+	return s.postComment("delete", commentData(msgDataID, index, userCommentID), ErrDeleteComment)
+}
+
+// ReplyComment 回复评论
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) ReplyComment(msgDataID int64, index int, userCommentID int64, content string) error {
+	data := commentData(msgDataID, index, userCommentID)
+	data["content"] = content
+	return s.postComment("reply/add", data, ErrReplyComment)
+}
+
+// DeleteCommentReply 删除评论的回复
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Comments_management/Image_Comments_Management_Interface.html
+func (s *SDK) DeleteCommentReply(msgDataID int64, index int, userCommentID int64) error {
+	return s.postComment("reply/delete", commentData(msgDataID, index, userCommentID), ErrDeleteCommentReply)
+}
+
+// commentData 指定单条评论的请求参数
+func commentData(msgDataID int64, index int, userCommentID int64) map[string]interface{} {
+	return map[string]interface{}{
+		"msg_data_id":     msgDataID,
+		"index":           index,
+		"user_comment_id": userCommentID,
+	}
+}
+
+// postComment 调用只返回错误码的评论管理接口，path为 comment/ 之后的路径
+func (s *SDK) postComment(path string, data map[string]interface{}, errAction string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/comment/%s?access_token=%s", path, s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(errAction, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// CommentIterator 基于offset分页的评论迭代器，每次拉取50条
+//
+//	it := sdk.Comments(ctx, msgDataID, 0, wechat.CommentTypeAll, 0)
+//	for it.Next() {
+//		fmt.Println(it.Item().Content)
+//	}
+//	if err := it.Err(); err != nil {
+//		// 可保存 it.Offset() 以便稍后继续
+//	}
+//
+// 迭代器按offset分页，遍历过程中删除评论会使后续评论前移，导致部分评论被跳过
+// 需要删除时应先遍历收集评论，结束后再调用 DeleteComment
+type CommentIterator struct {
+	offsetPager
+	fetch func(offset int) (*ListCommentResponse, error)
+	page  []Comment
+}
+
+// Comments 返回指定文章全部评论的迭代器，自动处理分页，offset为起始位置
+// 遍历过程中不要删除评论，否则offset会偏移导致漏掉评论
+func (s *SDK) Comments(ctx context.Context, msgDataID int64, index, commentType, offset int) *CommentIterator {
+	return &CommentIterator{
+		offsetPager: newOffsetPager(ctx, offset),
+		fetch: func(offset int) (*ListCommentResponse, error) {
+			return s.ListComment(msgDataID, index, offset, listCommentLimit, commentType)
+		},
+	}
+}
+
+// Next 移动到下一条评论，没有更多数据、出错或ctx被取消时返回false
+func (it *CommentIterator) Next() bool {
+	return it.next(func(offset int) (int, int, error) {
+		resp, err := it.fetch(offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		it.page = resp.Comment
+		return len(resp.Comment), resp.Total, nil
+	})
+}
+
+// Item 返回当前的评论
+func (it *CommentIterator) Item() *Comment {
+	return &it.page[it.index-1]
+}
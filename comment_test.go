@@ -0,0 +1,73 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// commentServer 模拟查看评论接口，共total条评论，user_comment_id从1开始
+func commentServer(t *testing.T, total int, requests *[]map[string]int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]int64
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		*requests = append(*requests, body)
+		resp := ListCommentResponse{Total: total}
+		for i := body["begin"]; i < body["begin"]+body["count"] && i < int64(total); i++ {
+			resp.Comment = append(resp.Comment, Comment{UserCommentID: i + 1, CommentType: int(i % 2)})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func TestComments(t *testing.T) {
+	var requests []map[string]int64
+	sdk := newTestSDK(t, commentServer(t, 120, &requests))
+
+	it := sdk.Comments(context.Background(), 2247483650, 1, CommentTypeElected, 10)
+	var ids []int64
+	for it.Next() {
+		ids = append(ids, it.Item().UserCommentID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 110 || ids[0] != 11 || ids[109] != 120 {
+		t.Fatalf("Comments() yielded %d comments, first %v", len(ids), ids[:1])
+	}
+	if it.Offset() != 120 {
+		t.Fatalf("Offset() = %d, want 120", it.Offset())
+	}
+	// 每页50条，从offset 10开始
+	wantBegins := []int64{10, 60, 110}
+	if len(requests) != len(wantBegins) {
+		t.Fatalf("requests = %v", requests)
+	}
+	for i, request := range requests {
+		if request["begin"] != wantBegins[i] || request["count"] != listCommentLimit ||
+			request["msg_data_id"] != 2247483650 || request["index"] != 1 || request["type"] != CommentTypeElected {
+			t.Fatalf("requests[%d] = %v", i, request)
+		}
+	}
+}
+
+func TestCommentsCanceled(t *testing.T) {
+	var requests []map[string]int64
+	sdk := newTestSDK(t, commentServer(t, 120, &requests))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	it := sdk.Comments(ctx, 2247483650, 0, CommentTypeAll, 0)
+	count := 0
+	for it.Next() {
+		if count++; count == 50 {
+			cancel()
+		}
+	}
+	if it.Err() != context.Canceled || count != 50 {
+		t.Fatalf("Comments() yielded %d comments, error %v", count, it.Err())
+	}
+}
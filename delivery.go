@@ -0,0 +1,207 @@
+package wechat
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeliveryStatus 模版消息送达状态
+type DeliveryStatus string
+
+const (
+	DeliveryPending      DeliveryStatus = "pending"       // 已发送，等待TEMPLATESENDJOBFINISH事件
+	DeliverySuccess      DeliveryStatus = "success"       // 送达成功
+	DeliveryUserBlock    DeliveryStatus = "user_block"    // 用户拒收（用户设置拒绝接收公众号消息）
+	DeliverySystemFailed DeliveryStatus = "system_failed" // 其他原因发送失败
+)
+
+// ErrDeliveryNotFound 未找到消息对应的送达记录
+var ErrDeliveryNotFound = errors.New("送达记录不存在")
+
+// parseDeliveryStatus 将事件推送中的Status转换为送达状态
+func parseDeliveryStatus(status string) DeliveryStatus {
+	status = strings.ToLower(strings.ReplaceAll(status, " ", ""))
+	switch {
+	case status == "success":
+		return DeliverySuccess
+	case strings.Contains(status, "userblock"):
+		return DeliveryUserBlock
+	default:
+		return DeliverySystemFailed
+	}
+}
+
+// DeliveryRecord 模版消息送达记录
+type DeliveryRecord struct {
+	MsgID      int64          `json:"msgid"`       // 消息ID
+	ToUser     string         `json:"touser"`      // 接收者openid
+	TemplateID string         `json:"template_id"` // 模版ID
+	Status     DeliveryStatus `json:"status"`      // 送达状态
+	RawStatus  string         `json:"raw_status"`  // 事件推送中的原始状态
+	SentAt     time.Time      `json:"sent_at"`     // 发送时间
+	FinishedAt time.Time      `json:"finished_at"` // 收到发送完成事件的时间
+}
+
+// DeliveryStore 送达记录存储，可自行实现以持久化到数据库等
+type DeliveryStore interface {
+	// Save 保存或覆盖送达记录
+	Save(record DeliveryRecord) error
+	// Get 获取送达记录，不存在时返回 ErrDeliveryNotFound
+	Get(msgID int64) (DeliveryRecord, error)
+	// Range 遍历所有送达记录，fn返回false时停止遍历
+	Range(fn func(record DeliveryRecord) bool) error
+}
+
+// MemoryDeliveryStore 基于内存的送达记录存储
+type MemoryDeliveryStore struct {
+	mutex   sync.RWMutex
+	records map[int64]DeliveryRecord
+}
+
+// NewMemoryDeliveryStore 实例化内存送达记录存储
+func NewMemoryDeliveryStore() *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{records: make(map[int64]DeliveryRecord)}
+}
+
+func (m *MemoryDeliveryStore) Save(record DeliveryRecord) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.records[record.MsgID] = record
+	return nil
+}
+
+func (m *MemoryDeliveryStore) Get(msgID int64) (DeliveryRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	record, ok := m.records[msgID]
+	if !ok {
+		return DeliveryRecord{}, ErrDeliveryNotFound
+	}
+	return record, nil
+}
+
+func (m *MemoryDeliveryStore) Range(fn func(record DeliveryRecord) bool) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, record := range m.records {
+		if !fn(record) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeliveryStats 送达情况统计
+type DeliveryStats struct {
+	Total        int     // 发送总数
+	Pending      int     // 等待结果数
+	Success      int     // 送达成功数
+	UserBlock    int     // 用户拒收数
+	SystemFailed int     // 其他原因失败数
+	SuccessRate  float64 // 送达率，按已收到结果的消息计算
+}
+
+// DeliveryTracker 模版消息送达跟踪
+// 通过 SendTempMessage 发送的消息会被记录，收到 TEMPLATESENDJOBFINISH 事件后更新送达状态
+type DeliveryTracker struct {
+	OnError func(err error) // 记录发送或处理事件失败时的回调，为nil时忽略错误
+
+	store DeliveryStore
+	mutex sync.Mutex // 发送记录与事件可能同时到达，读写记录时加锁
+}
+
+// NewDeliveryTracker 为SDK开启模版消息送达跟踪，store为nil时使用内存存储
+// 每个SDK只会开启一次，重复调用时返回已有的跟踪器并忽略store
+func NewDeliveryTracker(sdk *SDK, store DeliveryStore) *DeliveryTracker {
+	sdk.trackerOnce.Do(func() {
+		if store == nil {
+			store = NewMemoryDeliveryStore()
+		}
+		t := &DeliveryTracker{store: store}
+		sdk.tracker = t
+		sdk.addHook(func(msg *Message) {
+			if msg.Type == EventMessage && msg.Event == EventTemplateSendJobFinish {
+				t.handleError(t.HandleEvent(msg))
+			}
+		})
+	})
+	return sdk.tracker
+}
+
+// record 记录一次发送，发送完成事件可能先于发送接口返回到达，此时只补充发送信息
+func (t *DeliveryTracker) record(message *TempMessage, msgID int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	record, err := t.store.Get(msgID)
+	if errors.Is(err, ErrDeliveryNotFound) {
+		record, err = DeliveryRecord{MsgID: msgID, Status: DeliveryPending}, nil
+	}
+	if err != nil {
+		t.handleError(err)
+		return
+	}
+	record.ToUser = message.ToUser
+	record.TemplateID = message.TemplateID
+	record.SentAt = time.Now()
+	t.handleError(t.store.Save(record))
+}
+
+// HandleEvent 处理模版消息发送完成事件，SDK收到事件时会自动调用
+// 事件先于发送记录到达时会先保存送达结果，发送接口返回后再补充接收者和模版ID
+func (t *DeliveryTracker) HandleEvent(msg *Message) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	record, err := t.store.Get(msg.MsgID)
+	if errors.Is(err, ErrDeliveryNotFound) {
+		record, err = DeliveryRecord{MsgID: msg.MsgID, ToUser: msg.FromUserName}, nil
+	}
+	if err != nil {
+		return err
+	}
+	record.Status = parseDeliveryStatus(msg.Status)
+	record.RawStatus = msg.Status
+	record.FinishedAt = time.Now()
+	return t.store.Save(record)
+}
+
+func (t *DeliveryTracker) handleError(err error) {
+	if err != nil && t.OnError != nil {
+		t.OnError(err)
+	}
+}
+
+// Status 查询单条消息的送达记录
+func (t *DeliveryTracker) Status(msgID int64) (DeliveryRecord, error) {
+	return t.store.Get(msgID)
+}
+
+// Stats 统计送达情况，templateID为空时统计全部模版
+func (t *DeliveryTracker) Stats(templateID string) (*DeliveryStats, error) {
+	stats := &DeliveryStats{}
+	err := t.store.Range(func(record DeliveryRecord) bool {
+		if templateID != "" && record.TemplateID != templateID {
+			return true
+		}
+		stats.Total++
+		switch record.Status {
+		case DeliveryPending:
+			stats.Pending++
+		case DeliverySuccess:
+			stats.Success++
+		case DeliveryUserBlock:
+			stats.UserBlock++
+		default:
+			stats.SystemFailed++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if finished := stats.Total - stats.Pending; finished > 0 {
+		stats.SuccessRate = float64(stats.Success) / float64(finished)
+	}
+	return stats, nil
+}
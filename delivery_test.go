@@ -0,0 +1,82 @@
+package wechat
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// failingDeliveryStore 保存时总是失败的存储
+type failingDeliveryStore struct {
+	*MemoryDeliveryStore
+}
+
+func (failingDeliveryStore) Save(record DeliveryRecord) error {
+	return errors.New("store unavailable")
+}
+
+func templateSendJobFinish(msgID int64, status string) *Message {
+	return &Message{Type: EventMessage, Event: EventTemplateSendJobFinish, MsgID: msgID, Status: status, FromUserName: "openid"}
+}
+
+func TestDeliveryTrackerEventBeforeRecord(t *testing.T) {
+	var sdk *SDK
+	sdk = newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		// 发送完成事件先于发送接口的响应到达
+		sdk.runHooks(templateSendJobFinish(1001, "success"))
+		w.Write([]byte(`{"errcode":0,"msgid":1001}`))
+	})
+	tracker := NewDeliveryTracker(sdk, nil)
+	if _, err := sdk.SendTempMessage(&TempMessage{ToUser: "openid", TemplateID: "template"}); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := tracker.Status(1001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != DeliverySuccess || record.TemplateID != "template" || record.SentAt.IsZero() {
+		t.Fatalf("record = %+v", record)
+	}
+}
+
+func TestDeliveryTrackerStats(t *testing.T) {
+	sdk := New("", "")
+	tracker := NewDeliveryTracker(sdk, nil)
+	// 重复调用返回同一个跟踪器，事件不会被重复统计
+	if NewDeliveryTracker(sdk, nil) != tracker {
+		t.Fatal("NewDeliveryTracker returned a new tracker")
+	}
+	for i, status := range []string{"success", "failed:user block", "failed: system failed"} {
+		tracker.record(&TempMessage{ToUser: "openid", TemplateID: "template"}, int64(i))
+		sdk.runHooks(templateSendJobFinish(int64(i), status))
+	}
+	tracker.record(&TempMessage{ToUser: "openid", TemplateID: "template"}, 3)
+
+	stats, err := tracker.Stats("template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeliveryStats{Total: 4, Pending: 1, Success: 1, UserBlock: 1, SystemFailed: 1, SuccessRate: 1.0 / 3}
+	if *stats != want {
+		t.Fatalf("Stats() = %+v, want %+v", *stats, want)
+	}
+}
+
+func TestDeliveryTrackerRecordError(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":0,"msgid":1}`))
+	})
+	tracker := NewDeliveryTracker(sdk, failingDeliveryStore{NewMemoryDeliveryStore()})
+	var errs []error
+	tracker.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	// 消息已发送成功，记录失败不影响发送结果
+	if _, err := sdk.SendTempMessage(&TempMessage{ToUser: "openid"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("OnError called %d times", len(errs))
+	}
+}
@@ -0,0 +1,188 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// batchGetDraftLimit 获取草稿列表时单次请求的草稿数量上限
+const batchGetDraftLimit = 20
+
+// PicCrop 生成封面裁剪的坐标字段，坐标为相对于原图的比例（0到1），(x1, y1)为左上角，(x2, y2)为右下角
+func PicCrop(x1, y1, x2, y2 float64) string {
+	coordinates := []float64{x1, y1, x2, y2}
+	parts := make([]string, len(coordinates))
+	for i, coordinate := range coordinates {
+		parts[i] = strconv.FormatFloat(coordinate, 'f', -1, 64)
+	}
+	return strings.Join(parts, "_")
+}
+
+// AddDraft 新建草稿，返回草稿的media_id
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Draft_Box/Add_draft.html
+func (s *SDK) AddDraft(articles []Article) (string, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/draft/add?access_token=%s", s.AccessToken)
+
+	var responseJson AddDraftResponse
+	if err := postJSON(url, map[string]interface{}{"articles": articles}, &responseJson); err != nil {
+		return "", err
+	}
+	if responseJson.Errcode != 0 {
+		return "", ErrorHandler(ErrAddDraft, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.MediaID, nil
+}
+
+// GetDraft 获取草稿
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Draft_Box/Get_draft.html
+func (s *SDK) GetDraft(mediaID string) ([]Article, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/draft/get?access_token=%s", s.AccessToken)
+
+	var responseJson GetDraftResponse
+	if err := postJSON(url, map[string]interface{}{"media_id": mediaID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetDraft, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.NewsItem, nil
+}
+
+// UpdateDraft 修改草稿中的一篇文章，index为要更新的文章在图文消息中的位置，第一篇为0
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Draft_Box/Update_draft.html
+func (s *SDK) UpdateDraft(mediaID string, index int, article Article) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"media_id": mediaID,
+		"index":    index,
+		"articles": article,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/draft/update?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrUpdateDraft, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// DeleteDraft 删除草稿，此操作无法撤销
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Draft_Box/Delete_draft.html
+func (s *SDK) DeleteDraft(mediaID string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/draft/delete?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, map[string]interface{}{"media_id": mediaID}, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDeleteDraft, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// GetDraftCount 获取草稿的总数
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Draft_Box/Count_drafts.html
+func (s *SDK) GetDraftCount() (int, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return 0, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/draft/count?access_token=%s", s.AccessToken)
+
+	var responseJson GetDraftCountResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return 0, err
+	}
+	if responseJson.Errcode != 0 {
+		return 0, ErrorHandler(ErrGetDraftCount, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.TotalCount, nil
+}
+
+// BatchGetDraft 获取草稿列表，offset从0开始，count取值在1到20之间，noContent为true时不返回文章的content字段
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Draft_Box/Get_draft_list.html
+func (s *SDK) BatchGetDraft(offset, count int, noContent bool) (*BatchGetDraftResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"offset":     offset,
+		"count":      count,
+		"no_content": boolToInt(noContent),
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/draft/batchget?access_token=%s", s.AccessToken)
+
+	var responseJson BatchGetDraftResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrBatchGetDraft, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// DraftIterator 基于offset分页的草稿迭代器，每次拉取20个
+//
+//	it := sdk.Drafts(ctx, 0, true)
+//	for it.Next() {
+//		fmt.Println(it.Item().MediaID)
+//	}
+//	if err := it.Err(); err != nil {
+//		// 可保存 it.Offset() 以便稍后继续
+//	}
+type DraftIterator struct {
+	offsetPager
+	fetch func(offset int) (*BatchGetDraftResponse, error)
+	page  []DraftItem
+}
+
+// Drafts 返回全部草稿的迭代器，自动处理分页，offset为起始位置，noContent为true时不返回文章的content字段
+func (s *SDK) Drafts(ctx context.Context, offset int, noContent bool) *DraftIterator {
+	return &DraftIterator{
+		offsetPager: newOffsetPager(ctx, offset),
+		fetch: func(offset int) (*BatchGetDraftResponse, error) {
+			return s.BatchGetDraft(offset, batchGetDraftLimit, noContent)
+		},
+	}
+}
+
+// Next 移动到下一个草稿，没有更多数据、出错或ctx被取消时返回false
+func (it *DraftIterator) Next() bool {
+	return it.next(func(offset int) (int, int, error) {
+		resp, err := it.fetch(offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		it.page = resp.Item
+		return len(resp.Item), resp.TotalCount, nil
+	})
+}
+
+// Item 返回当前的草稿
+func (it *DraftIterator) Item() *DraftItem {
+	return &it.page[it.index-1]
+}
@@ -0,0 +1,61 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPicCrop(t *testing.T) {
+	if got := PicCrop(0, 0.125, 1, 0.5625); got != "0_0.125_1_0.5625" {
+		t.Fatalf("PicCrop() = %q", got)
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	const total = 45
+	var requests []string
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var data struct {
+			Offset    int `json:"offset"`
+			Count     int `json:"count"`
+			NoContent int `json:"no_content"`
+		}
+		if r.URL.Path != "/cgi-bin/draft/batchget" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Error(err)
+			return
+		}
+		requests = append(requests, fmt.Sprintf("%d %d %d", data.Offset, data.Count, data.NoContent))
+		resp := BatchGetDraftResponse{TotalCount: total}
+		for i := data.Offset; i < total && i < data.Offset+data.Count; i++ {
+			item := DraftItem{MediaID: fmt.Sprintf("draft-%d", i)}
+			item.Content.NewsItem = []Article{{Title: fmt.Sprintf("标题%d", i)}}
+			resp.Item = append(resp.Item, item)
+		}
+		resp.ItemCount = len(resp.Item)
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	it := sdk.Drafts(context.Background(), 10, true)
+	n := 10
+	for it.Next() {
+		item := it.Item()
+		if item.MediaID != fmt.Sprintf("draft-%d", n) || item.Content.NewsItem[0].Title != fmt.Sprintf("标题%d", n) {
+			t.Fatalf("Item() = %+v, want draft-%d", item, n)
+		}
+		n++
+	}
+	if it.Err() != nil || n != total || it.Offset() != total {
+		t.Fatalf("got %d drafts, offset %d, err %v", n, it.Offset(), it.Err())
+	}
+	want := []string{"10 20 1", "30 20 1"}
+	if !reflect.DeepEqual(requests, want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+}
@@ -0,0 +1,43 @@
+package comment
+
+import (
+	"context"
+	"github.com/supercat0867/wechat"
+	"strings"
+	"testing"
+)
+
+// 遍历文章的评论，删除包含敏感词的评论并回复其他未回复的评论
+// 遍历过程中删除评论会使分页偏移，因此先收集需要删除的评论，遍历结束后再删除
+func TestModerateComments(t *testing.T) {
+	sdk := wechat.New("", "")
+	var msgDataID int64 = 2247483650
+
+	var spam []int64
+	it := sdk.Comments(context.Background(), msgDataID, 0, wechat.CommentTypeAll, 0)
+	for it.Next() {
+		comment := it.Item()
+		if strings.Contains(comment.Content, "广告") {
+			spam = append(spam, comment.UserCommentID)
+			continue
+		}
+		if comment.Reply == nil {
+			if err := sdk.ReplyComment(msgDataID, 0, comment.UserCommentID, "感谢留言"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, userCommentID := range spam {
+		if err := sdk.DeleteComment(msgDataID, 0, userCommentID); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	return
+}
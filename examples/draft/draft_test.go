@@ -0,0 +1,70 @@
+package draft
+
+import (
+	"context"
+	"github.com/supercat0867/wechat"
+	"testing"
+)
+
+// 新建草稿并遍历草稿箱
+func TestAddDraft(t *testing.T) {
+	sdk := wechat.New("", "")
+	mediaID, err := sdk.AddDraft([]wechat.Article{
+		{
+			Title:           "新品发布",
+			Author:          "小明",
+			Digest:          "今年最值得期待的新品",
+			Content:         "<p>正文内容</p>",
+			ThumbMediaID:    "THUMB_MEDIA_ID",
+			NeedOpenComment: 1,
+			PicCrop2351:     wechat.PicCrop(0, 0.1, 1, 0.525),
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(mediaID)
+
+	it := sdk.Drafts(context.Background(), 0, true)
+	for it.Next() {
+		t.Log(it.Item().MediaID, it.Item().Content.NewsItem[0].Title)
+	}
+	if err = it.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+	return
+}
+
+// 将Markdown转换为图文消息并新建草稿，文中的本地及网络图片会自动上传
+func TestConvertMarkdown(t *testing.T) {
+	sdk := wechat.New("", "")
+	converter := wechat.NewMarkdownConverter(sdk)
+	converter.Theme = wechat.TechMarkdownTheme().Extend(map[string]string{
+		"h2": "margin: 1.2em 0 0.8em; font-size: 20px; font-weight: bold; color: #07c160;",
+	})
+	converter.UploadOptions = []wechat.UploadOption{wechat.WithAutoResize()}
+
+	// 也可通过 os.ReadFile 读取Markdown文件，本地图片的相对路径以 converter.BaseDir 为根目录
+	source := []byte("# 版本发布说明\n\n" +
+		"## 新功能\n\n" +
+		"- 支持**草稿箱**管理\n" +
+		"- 支持 [Markdown](https://commonmark.org) 排版\n\n" +
+		"```go\nsdk := wechat.New(appID, appSecret)\n```\n")
+	article, err := converter.ConvertArticle(context.Background(), source)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	article.Author = "小明"
+	article.ThumbMediaID = "THUMB_MEDIA_ID"
+
+	mediaID, err := sdk.AddDraft([]wechat.Article{*article})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(mediaID)
+	return
+}
@@ -0,0 +1,121 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/supercat0867/wechat"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+	"time"
+)
+
+// 上传程序生成的图片为临时素材，并构造被动回复图片消息
+func TestUploadTempMedia(t *testing.T) {
+	sdk := wechat.New("", "")
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for x := 0; x < 100; x++ {
+		img.Set(x, x, color.Black)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Error(err)
+		return
+	}
+	// 超出10MB时自动缩小图片
+	media, err := sdk.UploadTempMedia(wechat.MediaTypeImage, "generated.png", "image/png", &buf, wechat.WithAutoResize())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(media.MediaID, media.ExpiresAt())
+	t.Log(sdk.BuildImageResponse("obIt16lHlQiZpT5MYC_lTfFv7ZSA", "gh_123456789abc", media.MediaID))
+	return
+}
+
+// 下载临时素材到本地文件
+func TestDownloadMedia(t *testing.T) {
+	sdk := wechat.New("", "")
+	var buf bytes.Buffer
+	file, err := sdk.DownloadMedia("MEDIA_ID", &buf)
+	var apiErr *wechat.APIError
+	if errors.As(err, &apiErr) && apiErr.Errcode == 40007 {
+		t.Log("素材不存在或已过期")
+		return
+	}
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(file.Filename, file.ContentType, file.Size)
+	return
+}
+
+// 遍历全部永久图片素材
+func TestMaterials(t *testing.T) {
+	sdk := wechat.New("", "")
+	count, err := sdk.GetMaterialCount()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log("图片素材总数：", count.ImageCount)
+	it := sdk.Materials(context.Background(), wechat.MediaTypeImage, 0)
+	for it.Next() {
+		t.Log(it.Item().MediaID, it.Item().Name, it.Item().URL)
+	}
+	if err = it.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+	return
+}
+
+// 重复发送同一张图片时只上传一次
+func TestEnsureMedia(t *testing.T) {
+	sdk := wechat.New("", "")
+	cache := wechat.NewMediaCache(sdk, wechat.NewMemoryMediaCacheStore())
+	banner, err := os.ReadFile("banner.jpg")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 3; i++ {
+		media, err := cache.EnsureMedia(context.Background(), bytes.NewReader(banner))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		t.Log(media.MediaID, media.ExpiresAt())
+	}
+	return
+}
+
+// 上传本地视频为永久素材，显示上传进度，超时后取消上传
+func TestUploadVideoMaterial(t *testing.T) {
+	sdk := wechat.New("", "")
+	file, err := os.Open("intro.mp4")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer file.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resp, err := sdk.UploadVideoMaterial("intro.mp4", "video/mp4", file,
+		wechat.VideoDescription{Title: "产品介绍", Introduction: "一分钟了解我们的产品"},
+		wechat.WithContext(ctx),
+		wechat.WithProgress(func(uploaded, total int64) {
+			t.Logf("已上传 %d/%d", uploaded, total)
+		}),
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(resp.MediaId)
+	return
+}
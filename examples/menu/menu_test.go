@@ -10,18 +10,81 @@ func TestCreateMenu(t *testing.T) {
 	sdk := wechat.New("", "")
 	menu := wechat.Menu{
 		Button: []wechat.MenuButton{
-			{
-				Type:     "miniprogram",
-				Name:     "小程序",
-				Url:      "http://mp.weixin.qq.com",
-				AppID:    "",
-				PagePath: "pages/index/index",
-			},
+			wechat.MiniProgramButton("小程序", "http://mp.weixin.qq.com", "wx286b93c14bbf93aa", "pages/index/index"),
+			wechat.ParentButton("更多",
+				wechat.ViewButton("官网", "https://www.example.com"),
+				wechat.ClickButton("联系客服", "CONTACT_US"),
+			),
 		},
 	}
+	// 提交前在本地校验菜单配置
+	if err := menu.Validate(); err != nil {
+		t.Error(err)
+		return
+	}
 	if err := sdk.CreateMenu(menu); err != nil {
 		t.Error(err)
 		return
 	}
 	return
 }
+
+// 为指定标签的用户创建个性化菜单
+func TestAddConditionalMenu(t *testing.T) {
+	sdk := wechat.New("", "")
+	menuID, err := sdk.AddConditionalMenu(wechat.ConditionalMenu{
+		Button: []wechat.MenuButton{
+			wechat.ClickButton("VIP专享", "VIP_GIFT"),
+		},
+		MatchRule: wechat.MatchRule{
+			TagID:              "100",
+			ClientPlatformType: wechat.ClientPlatformIOS,
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// 测试用户匹配到的菜单
+	menu, err := sdk.TryMatchMenu("obIt16lHlQiZpT5MYC_lTfFv7ZSA")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(menuID, menu)
+	return
+}
+
+// 根据菜单配置生成变更计划，有变化时再执行
+func TestPlanMenu(t *testing.T) {
+	sdk := wechat.New("", "")
+	spec, err := wechat.ParseMenuSpecYAML([]byte(`
+menu:
+  button:
+    - type: click
+      name: 联系客服
+      key: CONTACT_US
+conditionalmenu:
+  - matchrule:
+      tag_id: "100"
+    button:
+      - type: click
+        name: VIP专享
+        key: VIP_GIFT
+`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	plan, err := sdk.PlanMenu(spec)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(plan)
+	if err = sdk.ApplyMenuPlan(plan); err != nil {
+		t.Error(err)
+		return
+	}
+	return
+}
@@ -17,10 +17,10 @@ func main() {
 	tempMessage := sdk.NewTemMessage("obIt16lHlQiZpT5MYC_lTfFv7ZSA", "IWMM8w9XD3jqc01gXyisvG6Y6yPMfGhlGyLPWimAN2w",
 		"www.baidu.com", "", "", "", data)
 	// 发送模版消息
-	err := sdk.SendTempMessage(tempMessage)
+	msgID, err := sdk.SendTempMessage(tempMessage)
 	if err != nil {
 		panic(err)
 	} else {
-		fmt.Println("模版消息发送成功！")
+		fmt.Println("模版消息发送成功！消息ID：", msgID)
 	}
 }
@@ -0,0 +1,44 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"github.com/supercat0867/wechat"
+	"testing"
+	"time"
+)
+
+// 发布草稿并等待发布完成
+func TestPublishAndWait(t *testing.T) {
+	sdk := wechat.New("", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	info, err := sdk.PublishAndWait(ctx, "DRAFT_MEDIA_ID")
+	if errors.Is(err, wechat.ErrPublishFailed) {
+		t.Error(info.PublishStatus, info.FailIdx)
+		return
+	}
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, item := range info.ArticleDetail.Item {
+		t.Log(item.Idx, item.ArticleURL)
+	}
+	return
+}
+
+// 遍历全部已发布文章
+func TestPublished(t *testing.T) {
+	sdk := wechat.New("", "")
+	it := sdk.Published(context.Background(), 0, true)
+	for it.Next() {
+		t.Log(it.Item().ArticleID, it.Item().Content.NewsItem[0].Title)
+	}
+	if err := it.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+	return
+}
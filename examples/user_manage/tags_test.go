@@ -0,0 +1,30 @@
+package user_manage
+
+import (
+	"context"
+	"github.com/supercat0867/wechat"
+	"testing"
+)
+
+// 创建标签并为用户打标签
+func TestTagging(t *testing.T) {
+	sdk := wechat.New("", "")
+	tag, err := sdk.CreateTag("VIP")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = sdk.BatchTagging(tag.ID, []string{"obIt16lHlQiZpT5MYC_lTfFv7ZSA"}); err != nil {
+		t.Error(err)
+		return
+	}
+	// 遍历标签下的粉丝
+	it := sdk.TagUsers(context.Background(), tag.ID, "")
+	for it.Next() {
+		t.Log(it.OpenID())
+	}
+	if err = it.Err(); err != nil {
+		t.Error(err)
+	}
+	return
+}
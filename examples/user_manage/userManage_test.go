@@ -1,6 +1,7 @@
 package user_manage
 
 import (
+	"context"
 	"github.com/supercat0867/wechat"
 	"testing"
 )
@@ -22,3 +23,20 @@ func TestGetUserInfo(t *testing.T) {
 	t.Log(info)
 	return
 }
+
+// 遍历全部关注者
+func TestFollowers(t *testing.T) {
+	sdk := wechat.New("", "")
+	it := sdk.Followers(context.Background(), "")
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		// 保存游标，下次可通过 sdk.Followers(ctx, cursor) 从中断处继续
+		t.Errorf("遍历中断于%s：%v", it.Cursor(), err)
+		return
+	}
+	t.Logf("共%d个关注者", count)
+	return
+}
@@ -0,0 +1,456 @@
+package wechat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FollowerStore 关注者本地存储，可自行实现以持久化到数据库等
+type FollowerStore interface {
+	// Get 获取用户信息，第二个返回值表示用户是否存在
+	Get(openID string) (UserInfo, bool, error)
+	// Put 保存或覆盖用户信息
+	Put(info UserInfo) error
+	// Delete 删除用户信息
+	Delete(openID string) error
+	// Range 遍历全部用户，fn返回false时停止遍历
+	Range(fn func(info UserInfo) bool) error
+}
+
+// Flusher 需要在同步结束后落盘的存储可实现该接口
+type Flusher interface {
+	Flush() error
+}
+
+// MemoryFollowerStore 基于内存的关注者存储
+type MemoryFollowerStore struct {
+	mutex sync.RWMutex
+	users map[string]UserInfo
+}
+
+// NewMemoryFollowerStore 实例化内存关注者存储
+func NewMemoryFollowerStore() *MemoryFollowerStore {
+	return &MemoryFollowerStore{users: make(map[string]UserInfo)}
+}
+
+func (m *MemoryFollowerStore) Get(openID string) (UserInfo, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	info, ok := m.users[openID]
+	return info, ok, nil
+}
+
+func (m *MemoryFollowerStore) Put(info UserInfo) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.users[info.OpenID] = info
+	return nil
+}
+
+func (m *MemoryFollowerStore) Delete(openID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.users, openID)
+	return nil
+}
+
+func (m *MemoryFollowerStore) Range(fn func(info UserInfo) bool) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, info := range m.users {
+		if !fn(info) {
+			break
+		}
+	}
+	return nil
+}
+
+// FileFollowerStore 基于文件的关注者存储，数据保存在内存中，调用 Flush 时以JSON Lines格式写入文件
+type FileFollowerStore struct {
+	*MemoryFollowerStore
+	path string
+}
+
+// NewFileFollowerStore 实例化文件关注者存储，文件存在时加载已有数据
+func NewFileFollowerStore(path string) (*FileFollowerStore, error) {
+	store := &FileFollowerStore{
+		MemoryFollowerStore: NewMemoryFollowerStore(),
+		path:                path,
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var info UserInfo
+		if err = decoder.Decode(&info); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		store.users[info.OpenID] = info
+	}
+	return store, nil
+}
+
+// Flush 将数据写入文件，先写入临时文件再替换，避免写入中断损坏已有数据
+func (f *FileFollowerStore) Flush() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	f.mutex.RLock()
+	for _, info := range f.users {
+		if err = encoder.Encode(info); err != nil {
+			break
+		}
+	}
+	f.mutex.RUnlock()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// FollowerChangeType 关注者变化类型
+type FollowerChangeType string
+
+const (
+	FollowerAdded         FollowerChangeType = "follow"   // 新增关注
+	FollowerRemoved       FollowerChangeType = "unfollow" // 取消关注
+	FollowerTagsChanged   FollowerChangeType = "tags"     // 标签变化
+	FollowerRemarkChanged FollowerChangeType = "remark"   // 备注变化
+)
+
+// FollowerChange 关注者的一次变化
+type FollowerChange struct {
+	Type   FollowerChangeType
+	OpenID string
+	Old    *UserInfo // 变化前的信息，新增关注时为nil
+	New    *UserInfo // 变化后的信息，取消关注时为nil
+}
+
+// FollowerSyncResult 一次全量同步的结果
+type FollowerSyncResult struct {
+	Total         int           // 当前关注者总数
+	Added         int           // 新增关注数
+	Removed       int           // 取消关注数
+	TagsChanged   int           // 标签变化数
+	RemarkChanged int           // 备注变化数
+	Duration      time.Duration // 同步耗时
+}
+
+// followerEventQueueSize 关注者镜像待处理事件队列的长度
+const followerEventQueueSize = 1024
+
+// ErrFollowerEventQueueFull 关注者镜像的事件队列已满，事件被丢弃，可在下一次 Sync 时补齐
+var ErrFollowerEventQueueFull = errors.New("关注者事件队列已满")
+
+// FollowerMirror 关注者本地镜像
+// Sync 通过关注者迭代器和批量获取用户信息进行全量同步，关注、取消关注事件会实时更新镜像，
+// 与上一次快照相比的变化通过 OnChange 回调通知；不再使用时需调用 Close 停止后台协程
+type FollowerMirror struct {
+	Concurrency int                         // 全量同步时的并发请求数，默认为4
+	Lang        string                      // 用户信息语言，默认为简体中文
+	OnChange    func(change FollowerChange) // 变化回调，同一时刻只会有一个回调在执行
+	OnError     func(err error)             // 后台处理关注、取消关注事件失败时的回调，为nil时忽略错误
+
+	sdk    *SDK
+	store  FollowerStore
+	mutex  sync.Mutex
+	seen   map[string]struct{} // 全量同步期间已确认关注的用户
+	events chan Message        // 待处理的关注、取消关注事件
+
+	queueMutex sync.RWMutex  // 保护 closed，避免向已关闭的队列写入事件
+	closed     bool          // 是否已调用 Close
+	stop       chan struct{} // Close 的ctx结束时关闭，丢弃队列中剩余的事件
+	stopOnce   sync.Once
+	done       chan struct{} // 后台协程退出时关闭
+}
+
+// NewFollowerMirror 实例化关注者镜像，并监听关注、取消关注事件，store为nil时使用内存存储
+// 事件在后台协程中按到达顺序处理，获取用户信息的请求不会占用微信回调的5秒响应时间
+// 存储实现了 Flusher 时，每处理完队列中积压的事件就落盘一次
+func NewFollowerMirror(sdk *SDK, store FollowerStore) *FollowerMirror {
+	if store == nil {
+		store = NewMemoryFollowerStore()
+	}
+	m := &FollowerMirror{
+		sdk:    sdk,
+		store:  store,
+		events: make(chan Message, followerEventQueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go m.processEvents()
+	sdk.addHook(func(msg *Message) {
+		if msg.Type != EventMessage || (msg.Event != EventSubscribe && msg.Event != EventUnsubscribe) {
+			return
+		}
+		m.queueMutex.RLock()
+		defer m.queueMutex.RUnlock()
+		if m.closed {
+			return
+		}
+		select {
+		case m.events <- *msg:
+		default:
+			m.handleError(ErrFollowerEventQueueFull)
+		}
+	})
+	return m
+}
+
+// processEvents 依次处理队列中的事件，队列暂时为空时落盘
+func (m *FollowerMirror) processEvents() {
+	defer close(m.done)
+	for msg := range m.events {
+		select {
+		case <-m.stop:
+			continue
+		default:
+		}
+		msg := msg
+		m.handleError(m.HandleEvent(&msg))
+		if len(m.events) == 0 {
+			m.handleError(m.flush())
+		}
+	}
+}
+
+// flush 存储实现了 Flusher 时将数据落盘
+func (m *FollowerMirror) flush() error {
+	if flusher, ok := m.store.(Flusher); ok {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close 停止接收新的事件，等待队列中已有的事件处理完成并落盘后退出后台协程
+// ctx结束时丢弃队列中剩余的事件并返回ctx.Err()，正在处理的事件完成后后台协程退出
+func (m *FollowerMirror) Close(ctx context.Context) error {
+	m.queueMutex.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.events)
+	}
+	m.queueMutex.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		m.stopOnce.Do(func() { close(m.stop) })
+		return ctx.Err()
+	}
+}
+
+func (m *FollowerMirror) handleError(err error) {
+	if err != nil && m.OnError != nil {
+		m.OnError(err)
+	}
+}
+
+// Store 返回镜像使用的存储
+func (m *FollowerMirror) Store() FollowerStore {
+	return m.store
+}
+
+// Sync 全量同步关注者，完成后删除本地存在但已不再关注的用户
+// 同步中途出错时不会删除任何用户，已更新的用户会保留
+func (m *FollowerMirror) Sync(ctx context.Context) (*FollowerSyncResult, error) {
+	start := time.Now()
+	result := &FollowerSyncResult{}
+	seen := make(map[string]struct{})
+	m.mutex.Lock()
+	m.seen = seen
+	m.mutex.Unlock()
+	defer func() {
+		m.mutex.Lock()
+		m.seen = nil
+		m.mutex.Unlock()
+	}()
+
+	err := m.sdk.ForEachFollowerInfo(ctx, FollowerInfoOptions{Concurrency: m.Concurrency, Lang: m.Lang}, func(info *UserInfo) error {
+		// 拉取列表后取消关注的用户，按未关注处理
+		if info.Subscribe == 0 {
+			return nil
+		}
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		seen[info.OpenID] = struct{}{}
+		changes, err := m.update(*info)
+		if err != nil {
+			return err
+		}
+		result.count(changes)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 找出已取消关注的用户
+	m.mutex.Lock()
+	var removed []UserInfo
+	err = m.store.Range(func(info UserInfo) bool {
+		if _, ok := seen[info.OpenID]; !ok {
+			removed = append(removed, info)
+		}
+		return true
+	})
+	if err != nil {
+		m.mutex.Unlock()
+		return nil, err
+	}
+	for i := range removed {
+		if err = m.store.Delete(removed[i].OpenID); err != nil {
+			m.mutex.Unlock()
+			return nil, err
+		}
+		m.emit(FollowerChange{Type: FollowerRemoved, OpenID: removed[i].OpenID, Old: &removed[i]})
+	}
+	result.Removed = len(removed)
+	result.Total = len(seen)
+	m.mutex.Unlock()
+
+	if err = m.flush(); err != nil {
+		return nil, err
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// HandleEvent 处理关注、取消关注事件，SDK收到事件时会自动在后台调用；直接调用时不会落盘
+func (m *FollowerMirror) HandleEvent(msg *Message) error {
+	switch msg.Event {
+	case EventSubscribe:
+		resp, err := m.sdk.GetUserInfoWithLang(msg.FromUserName, m.lang())
+		if err != nil {
+			return err
+		}
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.seen != nil {
+			m.seen[msg.FromUserName] = struct{}{}
+		}
+		_, err = m.update(resp.UserInfo)
+		return err
+	case EventUnsubscribe:
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.seen != nil {
+			delete(m.seen, msg.FromUserName)
+		}
+		old, ok, err := m.store.Get(msg.FromUserName)
+		if err != nil || !ok {
+			return err
+		}
+		if err = m.store.Delete(msg.FromUserName); err != nil {
+			return err
+		}
+		m.emit(FollowerChange{Type: FollowerRemoved, OpenID: msg.FromUserName, Old: &old})
+	}
+	return nil
+}
+
+func (m *FollowerMirror) lang() string {
+	if m.Lang == "" {
+		return LangZhCN
+	}
+	return m.Lang
+}
+
+// update 保存用户信息并通知变化，调用方需持有锁
+func (m *FollowerMirror) update(info UserInfo) ([]FollowerChange, error) {
+	old, ok, err := m.store.Get(info.OpenID)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.store.Put(info); err != nil {
+		return nil, err
+	}
+
+	var changes []FollowerChange
+	if !ok {
+		changes = append(changes, FollowerChange{Type: FollowerAdded, OpenID: info.OpenID, New: &info})
+	} else {
+		if !sameTagIDs(old.TagIDList, info.TagIDList) {
+			changes = append(changes, FollowerChange{Type: FollowerTagsChanged, OpenID: info.OpenID, Old: &old, New: &info})
+		}
+		if old.Remark != info.Remark {
+			changes = append(changes, FollowerChange{Type: FollowerRemarkChanged, OpenID: info.OpenID, Old: &old, New: &info})
+		}
+	}
+	for _, change := range changes {
+		m.emit(change)
+	}
+	return changes, nil
+}
+
+func (m *FollowerMirror) emit(change FollowerChange) {
+	if m.OnChange != nil {
+		m.OnChange(change)
+	}
+}
+
+// count 统计变化数量
+func (r *FollowerSyncResult) count(changes []FollowerChange) {
+	for _, change := range changes {
+		switch change.Type {
+		case FollowerAdded:
+			r.Added++
+		case FollowerTagsChanged:
+			r.TagsChanged++
+		case FollowerRemarkChanged:
+			r.RemarkChanged++
+		}
+	}
+}
+
+// sameTagIDs 判断两个标签列表是否相同（忽略顺序）
+func sameTagIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]int(nil), a...)
+	y := append([]int(nil), b...)
+	sort.Ints(x)
+	sort.Ints(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
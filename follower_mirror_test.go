@@ -0,0 +1,177 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor 等待后台处理完成，超时时测试失败
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFollowerMirrorSync(t *testing.T) {
+	sdk := newTestSDK(t, followerServer(t, 250))
+	store := NewMemoryFollowerStore()
+	store.Put(UserInfo{Subscribe: 1, OpenID: "openid-000", Remark: "旧备注"})
+	store.Put(UserInfo{Subscribe: 1, OpenID: "unsubscribed"})
+
+	mirror := NewFollowerMirror(sdk, store)
+	defer mirror.Close(context.Background())
+	var changes []FollowerChange
+	mirror.OnChange = func(change FollowerChange) {
+		changes = append(changes, change)
+	}
+	result, err := mirror.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 250 || result.Added != 249 || result.Removed != 1 || result.RemarkChanged != 1 {
+		t.Fatalf("Sync() = %+v", result)
+	}
+	if len(changes) != 251 {
+		t.Fatalf("OnChange called %d times, want 251", len(changes))
+	}
+	if _, ok, _ := store.Get("unsubscribed"); ok {
+		t.Fatal("unsubscribed follower was not removed")
+	}
+}
+
+func TestFollowerMirrorHandlesEventsInBackground(t *testing.T) {
+	release := make(chan struct{})
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		switch r.URL.Query().Get("openid") {
+		case "new":
+			w.Write([]byte(`{"subscribe":1,"openid":"new"}`))
+		default:
+			w.Write([]byte(`{"errcode":40003,"errmsg":"invalid openid"}`))
+		}
+	})
+	mirror := NewFollowerMirror(sdk, nil)
+	defer mirror.Close(context.Background())
+	var (
+		mutex sync.Mutex
+		errs  []error
+	)
+	mirror.OnError = func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs = append(errs, err)
+	}
+
+	// 钩子不等待获取用户信息的请求完成
+	done := make(chan struct{})
+	go func() {
+		sdk.runHooks(&Message{Type: EventMessage, Event: EventSubscribe, FromUserName: "new"})
+		sdk.runHooks(&Message{Type: EventMessage, Event: EventSubscribe, FromUserName: "invalid"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hook blocked on the user info request")
+	}
+	close(release)
+
+	waitFor(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(errs) == 1
+	})
+	var apiErr *APIError
+	if !errors.As(errs[0], &apiErr) || apiErr.Errcode != 40003 {
+		t.Fatalf("OnError() got %v", errs[0])
+	}
+	if _, ok, _ := mirror.Store().Get("new"); !ok {
+		t.Fatal("subscribed follower was not stored")
+	}
+
+	sdk.runHooks(&Message{Type: EventMessage, Event: EventUnsubscribe, FromUserName: "new"})
+	waitFor(t, func() bool {
+		_, ok, _ := mirror.Store().Get("new")
+		return !ok
+	})
+}
+
+func TestFollowerMirrorClose(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"subscribe":1,"openid":%q}`, r.URL.Query().Get("openid"))
+	})
+	path := filepath.Join(t.TempDir(), "followers.jsonl")
+	store, err := NewFileFollowerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror := NewFollowerMirror(sdk, store)
+	for i := 0; i < 5; i++ {
+		sdk.runHooks(&Message{Type: EventMessage, Event: EventSubscribe, FromUserName: fmt.Sprintf("openid-%d", i)})
+	}
+	// Close 等待队列中的事件处理完成并落盘
+	if err = mirror.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = mirror.Close(context.Background()); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	// 关闭后的事件被忽略
+	sdk.runHooks(&Message{Type: EventMessage, Event: EventSubscribe, FromUserName: "late"})
+
+	reloaded, err := NewFileFollowerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	reloaded.Range(func(info UserInfo) bool {
+		n++
+		return true
+	})
+	if n != 5 {
+		t.Fatalf("file contains %d followers, want 5", n)
+	}
+}
+
+func TestFollowerMirrorCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var requests int32
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprintf(w, `{"subscribe":1,"openid":%q}`, r.URL.Query().Get("openid"))
+	})
+	mirror := NewFollowerMirror(sdk, nil)
+	for i := 0; i < 3; i++ {
+		sdk.runHooks(&Message{Type: EventMessage, Event: EventSubscribe, FromUserName: fmt.Sprintf("openid-%d", i)})
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&requests) == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := mirror.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// 正在处理的事件完成后，剩余事件被丢弃，后台协程退出
+	close(release)
+	select {
+	case <-mirror.done:
+	case <-time.After(time.Second):
+		t.Fatal("background goroutine did not exit")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("processed %d events after Close, want 1", n)
+	}
+}
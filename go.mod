@@ -1,3 +1,9 @@
 module github.com/supercat0867/wechat
 
 go 1.20
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/yuin/goldmark v1.7.8
+
+require golang.org/x/net v0.35.0
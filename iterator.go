@@ -0,0 +1,138 @@
+package wechat
+
+import "context"
+
+// OpenIDIterator 基于next_openid分页的openid迭代器，按需逐页拉取，不会一次性加载全部openid
+//
+//	it := sdk.Followers(ctx, "")
+//	for it.Next() {
+//		fmt.Println(it.OpenID())
+//	}
+//	if err := it.Err(); err != nil {
+//		// 可保存 it.Cursor() 以便稍后继续
+//	}
+type OpenIDIterator struct {
+	ctx    context.Context
+	fetch  func(nextOpenID string) (openIDs []string, next string, err error)
+	page   []string // 当前页
+	index  int      // 当前页中下一个待返回的位置
+	next   string   // 拉取下一页时使用的next_openid
+	cursor string   // 最后一个返回的openid
+	done   bool
+	err    error
+}
+
+// newOpenIDIterator 实例化openid迭代器，start为起始openid（不包含），为空时从头开始
+func newOpenIDIterator(ctx context.Context, start string, fetch func(nextOpenID string) ([]string, string, error)) *OpenIDIterator {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &OpenIDIterator{
+		ctx:    ctx,
+		fetch:  fetch,
+		next:   start,
+		cursor: start,
+	}
+}
+
+// Next 移动到下一个openid，没有更多数据、出错或ctx被取消时返回false
+func (it *OpenIDIterator) Next() bool {
+	for it.index >= len(it.page) {
+		if it.done || it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		page, next, err := it.fetch(it.next)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		// 拉取数量为0时说明已经拉取完毕
+		if len(page) == 0 {
+			it.done = true
+			return false
+		}
+		// next_openid为空或没有前进时，当前页即为最后一页
+		if next == "" || next == it.next {
+			it.done = true
+		}
+		it.page, it.index, it.next = page, 0, next
+	}
+	it.cursor = it.page[it.index]
+	it.index++
+	return true
+}
+
+// OpenID 返回当前的openid
+func (it *OpenIDIterator) OpenID() string {
+	return it.cursor
+}
+
+// Err 返回迭代过程中遇到的错误
+func (it *OpenIDIterator) Err() error {
+	return it.err
+}
+
+// Cursor 返回最后一个已处理的openid，可作为起始openid恢复迭代
+func (it *OpenIDIterator) Cursor() string {
+	return it.cursor
+}
+
+// offsetPager 基于offset分页的迭代状态，由具体类型的迭代器嵌入
+type offsetPager struct {
+	ctx    context.Context
+	index  int // 当前页中下一个待返回的位置
+	size   int // 当前页的数量
+	offset int // 已返回的数量，即下一项的offset
+	done   bool
+	err    error
+}
+
+func newOffsetPager(ctx context.Context, offset int) offsetPager {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return offsetPager{ctx: ctx, offset: offset}
+}
+
+// next 移动到下一项，当前页已遍历完时调用fetch拉取下一页，fetch返回本页的数量及总数
+func (p *offsetPager) next(fetch func(offset int) (count, total int, err error)) bool {
+	if p.index >= p.size {
+		if p.done || p.err != nil {
+			return false
+		}
+		if err := p.ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+		count, total, err := fetch(p.offset)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		if count == 0 {
+			p.done = true
+			return false
+		}
+		if p.offset+count >= total {
+			p.done = true
+		}
+		p.index, p.size = 0, count
+	}
+	p.index++
+	p.offset++
+	return true
+}
+
+// Err 返回迭代过程中遇到的错误
+func (p *offsetPager) Err() error {
+	return p.err
+}
+
+// Offset 返回已处理的数量，可作为offset恢复迭代
+func (p *offsetPager) Offset() int {
+	return p.offset
+}
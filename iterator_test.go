@@ -0,0 +1,210 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// pagedOpenIDs 模拟next_openid分页，每页size个，返回拉取时使用的next_openid
+func pagedOpenIDs(openIDs []string, size int, requests *[]string) func(string) ([]string, string, error) {
+	return func(nextOpenID string) ([]string, string, error) {
+		*requests = append(*requests, nextOpenID)
+		start := 0
+		if nextOpenID != "" {
+			for i, openID := range openIDs {
+				if openID == nextOpenID {
+					start = i + 1
+				}
+			}
+		}
+		end := start + size
+		if end > len(openIDs) {
+			end = len(openIDs)
+		}
+		if start >= end {
+			return nil, "", nil
+		}
+		return openIDs[start:end], openIDs[end-1], nil
+	}
+}
+
+func TestOpenIDIterator(t *testing.T) {
+	openIDs := openIDList(7)
+	tests := []struct {
+		name         string
+		start        string
+		fetch        func(requests *[]string) func(string) ([]string, string, error)
+		want         []string
+		wantRequests []string
+	}{
+		{
+			name: "分页",
+			fetch: func(requests *[]string) func(string) ([]string, string, error) {
+				return pagedOpenIDs(openIDs, 3, requests)
+			},
+			want:         openIDs,
+			wantRequests: []string{"", openIDs[2], openIDs[5], openIDs[6]},
+		},
+		{
+			name:  "从游标继续",
+			start: openIDs[4],
+			fetch: func(requests *[]string) func(string) ([]string, string, error) {
+				return pagedOpenIDs(openIDs, 3, requests)
+			},
+			want:         openIDs[5:],
+			wantRequests: []string{openIDs[4], openIDs[6]},
+		},
+		{
+			name: "next_openid为空时结束",
+			fetch: func(requests *[]string) func(string) ([]string, string, error) {
+				return func(nextOpenID string) ([]string, string, error) {
+					*requests = append(*requests, nextOpenID)
+					return openIDs[:2], "", nil
+				}
+			},
+			want:         openIDs[:2],
+			wantRequests: []string{""},
+		},
+		{
+			name:  "next_openid没有前进时结束",
+			start: openIDs[0],
+			fetch: func(requests *[]string) func(string) ([]string, string, error) {
+				return func(nextOpenID string) ([]string, string, error) {
+					*requests = append(*requests, nextOpenID)
+					return openIDs[1:3], nextOpenID, nil
+				}
+			},
+			want:         openIDs[1:3],
+			wantRequests: []string{openIDs[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			it := newOpenIDIterator(context.Background(), tt.start, tt.fetch(&requests))
+			var got []string
+			for it.Next() {
+				got = append(got, it.OpenID())
+			}
+			if it.Err() != nil {
+				t.Fatal(it.Err())
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Fatalf("got %v with requests %v", got, requests)
+			}
+			if it.Next() {
+				t.Fatal("Next() = true after the last openid")
+			}
+		})
+	}
+}
+
+func TestOpenIDIteratorError(t *testing.T) {
+	openIDs := openIDList(5)
+	fetchErr := errors.New("fetch failed")
+	var requests []string
+	paged := pagedOpenIDs(openIDs, 2, &requests)
+	it := newOpenIDIterator(context.Background(), "", func(nextOpenID string) ([]string, string, error) {
+		if len(requests) == 2 {
+			return nil, "", fetchErr
+		}
+		return paged(nextOpenID)
+	})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if !errors.Is(it.Err(), fetchErr) || count != 4 || it.Cursor() != openIDs[3] {
+		t.Fatalf("count = %d, Err() = %v, Cursor() = %s", count, it.Err(), it.Cursor())
+	}
+
+	// 从游标继续时不会重复返回
+	resumed := newOpenIDIterator(context.Background(), it.Cursor(), pagedOpenIDs(openIDs, 2, &requests))
+	if !resumed.Next() || resumed.OpenID() != openIDs[4] || resumed.Next() {
+		t.Fatal("resumed iterator did not continue after the cursor")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := newOpenIDIterator(ctx, "", pagedOpenIDs(openIDs, 2, &requests))
+	if canceled.Next() || canceled.Err() != context.Canceled {
+		t.Fatalf("Err() = %v, want context.Canceled", canceled.Err())
+	}
+}
+
+func TestOffsetPager(t *testing.T) {
+	tests := []struct {
+		name         string
+		offset       int
+		total        int
+		items        int // 实际可拉取的数量，小于total时模拟遍历过程中数据被删除
+		want         int
+		wantRequests []int
+	}{
+		{"从头遍历", 0, 45, 45, 45, []int{0, 20, 40}},
+		{"从offset继续", 5, 45, 45, 40, []int{5, 25}},
+		{"整页结束", 0, 40, 40, 40, []int{0, 20}},
+		{"空列表", 0, 0, 0, 0, []int{0}},
+		{"数据减少", 0, 45, 30, 30, []int{0, 20, 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []int
+			fetch := func(offset int) (int, int, error) {
+				requests = append(requests, offset)
+				count := tt.items - offset
+				if count > 20 {
+					count = 20
+				}
+				if count < 0 {
+					count = 0
+				}
+				return count, tt.total, nil
+			}
+			pager := newOffsetPager(context.Background(), tt.offset)
+			n := 0
+			for pager.next(fetch) {
+				n++
+			}
+			if n != tt.want || pager.Offset() != tt.offset+tt.want || pager.Err() != nil {
+				t.Fatalf("got %d items, offset %d, err %v", n, pager.Offset(), pager.Err())
+			}
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Fatalf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestOffsetPagerError(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	calls := 0
+	fetch := func(offset int) (int, int, error) {
+		calls++
+		if offset == 20 {
+			return 0, 0, errFetch
+		}
+		return 20, 45, nil
+	}
+	pager := newOffsetPager(context.Background(), 0)
+	n := 0
+	for pager.next(fetch) {
+		n++
+	}
+	if n != 20 || pager.Offset() != 20 || !errors.Is(pager.Err(), errFetch) {
+		t.Fatalf("got %d items, offset %d, err %v", n, pager.Offset(), pager.Err())
+	}
+	// 出错后不再拉取
+	if pager.next(fetch) || calls != 2 {
+		t.Fatalf("next() after error fetched %d times", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pager = newOffsetPager(ctx, 0)
+	if pager.next(fetch) || !errors.Is(pager.Err(), context.Canceled) || calls != 2 {
+		t.Fatalf("next() with canceled ctx: err %v, calls %d", pager.Err(), calls)
+	}
+}
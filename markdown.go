@@ -0,0 +1,635 @@
+package wechat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// MarkdownTheme 将Markdown转换为图文消息正文时使用的主题
+// 图文消息不支持样式表，样式会以style属性写入每个元素
+type MarkdownTheme struct {
+	Name string // 主题名称
+	// Styles 元素对应的内联样式，键为 section（正文外层）、h1至h6、p、blockquote、pre、pre code（代码块）、
+	// code（行内代码）、strong、em、del、a、img、figure、figcaption（图片说明）、ul、ol、li、hr、table、th、td、
+	// sup（链接角标）、footnotes（文末参考资料）和 footnote（参考资料条目）
+	Styles map[string]string
+}
+
+// Extend 返回覆盖部分样式后的新主题，不会修改原主题
+func (t MarkdownTheme) Extend(styles map[string]string) MarkdownTheme {
+	extended := MarkdownTheme{Name: t.Name, Styles: make(map[string]string, len(t.Styles)+len(styles))}
+	for element, style := range t.Styles {
+		extended.Styles[element] = style
+	}
+	for element, style := range styles {
+		extended.Styles[element] = style
+	}
+	return extended
+}
+
+// DefaultMarkdownTheme 默认主题，黑白配色
+func DefaultMarkdownTheme() MarkdownTheme {
+	heading := "margin: 1em 0 0.6em; font-size: 16px; font-weight: bold;"
+	return MarkdownTheme{
+		Name: "default",
+		Styles: map[string]string{
+			"section":    "font-size: 16px; line-height: 1.75; color: #333; letter-spacing: 0.5px; word-break: break-all;",
+			"h1":         "margin: 1.2em 0 0.8em; font-size: 22px; font-weight: bold; text-align: center;",
+			"h2":         "margin: 1.2em 0 0.8em; padding-bottom: 6px; font-size: 20px; font-weight: bold; border-bottom: 1px solid #eaecef;",
+			"h3":         "margin: 1em 0 0.6em; font-size: 18px; font-weight: bold;",
+			"h4":         heading,
+			"h5":         heading,
+			"h6":         heading,
+			"p":          "margin: 0 0 1em;",
+			"blockquote": "margin: 0 0 1em; padding: 8px 16px; color: #666; background: #f7f7f7; border-left: 4px solid #ddd;",
+			"pre":        "margin: 0 0 1em; padding: 12px; overflow-x: auto; font-size: 13px; line-height: 1.6; background: #f6f8fa; border-radius: 4px;",
+			"pre code":   "font-family: Menlo, Consolas, monospace; white-space: nowrap;",
+			"code":       "padding: 2px 4px; font-size: 90%; font-family: Menlo, Consolas, monospace; color: #d14; background: #f6f8fa; border-radius: 3px;",
+			"strong":     "font-weight: bold;",
+			"em":         "font-style: italic;",
+			"del":        "text-decoration: line-through;",
+			"a":          "color: #576b95; text-decoration: none;",
+			"img":        "display: block; max-width: 100%; margin: 0 auto;",
+			"figure":     "margin: 0 0 1em; text-align: center;",
+			"figcaption": "margin-top: 6px; font-size: 13px; color: #999;",
+			"ul":         "margin: 0 0 1em; padding-left: 1.5em; list-style-type: disc;",
+			"ol":         "margin: 0 0 1em; padding-left: 1.5em; list-style-type: decimal;",
+			"li":         "margin: 0.3em 0;",
+			"hr":         "margin: 1.5em 0; border: 0; border-top: 1px solid #eee;",
+			"table":      "width: 100%; margin: 0 0 1em; border-collapse: collapse; font-size: 14px;",
+			"th":         "padding: 6px 10px; font-weight: bold; background: #f6f8fa; border: 1px solid #dfe2e5;",
+			"td":         "padding: 6px 10px; border: 1px solid #dfe2e5;",
+			"sup":        "font-size: 12px; color: #576b95;",
+			"footnotes":  "margin-top: 2em; color: #666;",
+			"footnote":   "margin: 0.3em 0; font-size: 13px; color: #666; word-break: break-all;",
+		},
+	}
+}
+
+// TechMarkdownTheme 技术主题，蓝色强调色及深色代码块
+func TechMarkdownTheme() MarkdownTheme {
+	theme := DefaultMarkdownTheme().Extend(map[string]string{
+		"h1":         "margin: 1.2em 0 0.8em; font-size: 22px; font-weight: bold; text-align: center; color: #1e6bb8;",
+		"h2":         "margin: 1.2em 0 0.8em; padding-left: 10px; font-size: 20px; font-weight: bold; color: #1e6bb8; border-left: 4px solid #1e6bb8;",
+		"h3":         "margin: 1em 0 0.6em; font-size: 18px; font-weight: bold; color: #1e6bb8;",
+		"blockquote": "margin: 0 0 1em; padding: 8px 16px; color: #555; background: #f2f7fb; border-left: 4px solid #1e6bb8;",
+		"pre":        "margin: 0 0 1em; padding: 12px; overflow-x: auto; font-size: 13px; line-height: 1.6; color: #abb2bf; background: #282c34; border-radius: 4px;",
+		"code":       "padding: 2px 4px; font-size: 90%; font-family: Menlo, Consolas, monospace; color: #1e6bb8; background: #f2f7fb; border-radius: 3px;",
+		"strong":     "font-weight: bold; color: #1e6bb8;",
+		"a":          "color: #1e6bb8; text-decoration: none; border-bottom: 1px solid #1e6bb8;",
+		"th":         "padding: 6px 10px; font-weight: bold; color: #fff; background: #1e6bb8; border: 1px solid #1e6bb8;",
+		"sup":        "font-size: 12px; color: #1e6bb8;",
+	})
+	theme.Name = "tech"
+	return theme
+}
+
+const (
+	// markdownImageTimeout 下载网络图片的超时时间
+	markdownImageTimeout = 30 * time.Second
+	// markdownImageMaxSize 网络图片的大小上限，超出上传限制的图片可通过 WithAutoResize 缩小
+	markdownImageMaxSize = 10 << 20
+)
+
+// MarkdownConverter 将Markdown转换为图文消息正文
+// 图片通过 UploadArticleImage 上传后替换为微信的图片地址；图文消息中只有公众号文章链接可以点击，其他链接会转换为文末的参考资料
+// Markdown中的HTML只保留常用的排版标签及style属性，<img>同样会上传，脚本等标签连同内容一起移除
+type MarkdownConverter struct {
+	Theme         MarkdownTheme  // 主题，默认为 DefaultMarkdownTheme
+	BaseDir       string         // 本地图片相对路径的根目录，为空时相对于当前工作目录
+	UploadOptions []UploadOption // 上传图片的选项，如 WithAutoResize
+	HTTPClient    *http.Client   // 下载网络图片的客户端，默认超时30秒
+
+	sdk      *SDK
+	markdown goldmark.Markdown
+	mutex    sync.Mutex
+	images   map[string]string // 已上传的图片，原地址到微信图片地址
+}
+
+// NewMarkdownConverter 实例化Markdown转换器，支持GFM的表格、删除线、任务列表及自动链接
+func NewMarkdownConverter(sdk *SDK) *MarkdownConverter {
+	return &MarkdownConverter{
+		Theme:      DefaultMarkdownTheme(),
+		HTTPClient: &http.Client{Timeout: markdownImageTimeout},
+		sdk:        sdk,
+		markdown:   goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		images:     make(map[string]string),
+	}
+}
+
+// Convert 将Markdown转换为图文消息正文HTML，并上传其中的本地及网络图片
+// 相同的图片在同一转换器中只上传一次，已是微信图片地址的不会重复上传
+func (c *MarkdownConverter) Convert(ctx context.Context, source []byte) (string, error) {
+	doc := c.markdown.Parser().Parse(text.NewReader(source))
+	htmlImages, err := c.rehostImages(ctx, doc, source)
+	if err != nil {
+		return "", err
+	}
+	return c.render(doc, source, htmlImages), nil
+}
+
+// ConvertArticle 将Markdown转换为图文消息，开头的一级标题作为文章标题且不出现在正文中
+// 返回的文章还需设置封面 ThumbMediaID 后才能通过 AddDraft 新建草稿
+func (c *MarkdownConverter) ConvertArticle(ctx context.Context, source []byte) (*Article, error) {
+	doc := c.markdown.Parser().Parse(text.NewReader(source))
+	var title string
+	if heading, ok := doc.FirstChild().(*ast.Heading); ok && heading.Level == 1 {
+		title = markdownPlainText(heading, source)
+		doc.RemoveChild(doc, heading)
+	}
+	htmlImages, err := c.rehostImages(ctx, doc, source)
+	if err != nil {
+		return nil, err
+	}
+	return &Article{
+		ArticleType: ArticleTypeNews,
+		Title:       title,
+		Content:     c.render(doc, source, htmlImages),
+	}, nil
+}
+
+// rehostImages 上传文档中的图片并替换图片地址，返回HTML中的图片地址到微信图片地址的映射
+func (c *MarkdownConverter) rehostImages(ctx context.Context, doc ast.Node, source []byte) (map[string]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var (
+		images     []*ast.Image
+		htmlImages = make(map[string]string)
+	)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if image, ok := n.(*ast.Image); ok {
+			images = append(images, image)
+		} else if fragment := markdownHTML(n, source); fragment != nil {
+			for _, src := range htmlImageSources(fragment) {
+				htmlImages[src] = ""
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, image := range images {
+		src := string(image.Destination)
+		imageURL, err := c.rehostImage(ctx, src)
+		if err != nil {
+			return nil, fmt.Errorf("图片%s上传失败：%w", src, err)
+		}
+		image.Destination = []byte(imageURL)
+	}
+	for src := range htmlImages {
+		imageURL, err := c.rehostImage(ctx, src)
+		if err != nil {
+			return nil, fmt.Errorf("图片%s上传失败：%w", src, err)
+		}
+		htmlImages[src] = imageURL
+	}
+	return htmlImages, nil
+}
+
+// rehostImage 上传单张图片，返回微信的图片地址
+func (c *MarkdownConverter) rehostImage(ctx context.Context, src string) (string, error) {
+	remote := strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+	if remote && isWeChatImage(src) {
+		return src, nil
+	}
+	key := src
+	if !remote {
+		key = strings.TrimPrefix(src, "file://")
+		if unescaped, err := url.PathUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !filepath.IsAbs(key) && c.BaseDir != "" {
+			key = filepath.Join(c.BaseDir, key)
+		}
+	}
+
+	c.mutex.Lock()
+	imageURL, ok := c.images[key]
+	c.mutex.Unlock()
+	if ok {
+		return imageURL, nil
+	}
+
+	opts := append([]UploadOption{WithContext(ctx)}, c.UploadOptions...)
+	var err error
+	if remote {
+		imageURL, err = c.uploadRemoteImage(ctx, src, opts)
+	} else {
+		imageURL, err = c.uploadLocalImage(key, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.mutex.Lock()
+	c.images[key] = imageURL
+	c.mutex.Unlock()
+	return imageURL, nil
+}
+
+func (c *MarkdownConverter) uploadRemoteImage(ctx context.Context, src string, opts []UploadOption) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", src, nil)
+	if err != nil {
+		return "", err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: markdownImageTimeout}
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("图片下载失败，状态码%d", resp.StatusCode)
+	}
+	if resp.ContentLength > markdownImageMaxSize {
+		return "", fmt.Errorf("%w：网络图片最大为%dMB", ErrMediaTooLarge, markdownImageMaxSize>>20)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, markdownImageMaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > markdownImageMaxSize {
+		return "", fmt.Errorf("%w：网络图片最大为%dMB", ErrMediaTooLarge, markdownImageMaxSize>>20)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	return c.sdk.UploadArticleImage(filenameFromURL(src, contentType), contentType, bytes.NewReader(data), opts...)
+}
+
+func (c *MarkdownConverter) uploadLocalImage(path string, opts []UploadOption) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return c.sdk.UploadArticleImage(filepath.Base(path), "", file, opts...)
+}
+
+// isWeChatImage 判断是否为微信的图片地址
+func isWeChatImage(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && isHTTPURL(u) && (u.Host == "qpic.cn" || strings.HasSuffix(u.Host, ".qpic.cn"))
+}
+
+// isWeChatArticleLink 判断是否为图文消息中可以点击的公众号文章链接
+func isWeChatArticleLink(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && isHTTPURL(u) && u.Host == "mp.weixin.qq.com"
+}
+
+// isHTTPURL 判断地址的协议是否为http或https，避免 javascript://mp.weixin.qq.com/ 之类的地址通过域名校验
+func isHTTPURL(u *url.URL) bool {
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// markdownFootnote 转换为参考资料的链接
+type markdownFootnote struct {
+	text string
+	url  string
+}
+
+// markdownRenderer 将Markdown语法树输出为带内联样式的HTML
+type markdownRenderer struct {
+	theme     MarkdownTheme
+	source    []byte
+	w         *bufio.Writer
+	footnotes []markdownFootnote
+	images    map[string]string // HTML中的图片地址到微信图片地址
+	skipTag   string            // 正在移除内容的HTML标签，如 script
+	anchors   []string          // HTML中未闭合的<a>实际输出的标签
+}
+
+func (c *MarkdownConverter) render(doc ast.Node, source []byte, images map[string]string) string {
+	var buf bytes.Buffer
+	r := &markdownRenderer{theme: c.Theme, source: source, w: bufio.NewWriter(&buf), images: images}
+	_ = ast.Walk(doc, r.renderNode)
+	_ = r.w.Flush()
+	return buf.String()
+}
+
+// open 输出带主题样式的开始标签，attrs为属性名和属性值交替的列表
+func (r *markdownRenderer) open(tag, element string, attrs ...string) {
+	r.openStyled(tag, r.theme.Styles[element], attrs...)
+}
+
+func (r *markdownRenderer) openStyled(tag, style string, attrs ...string) {
+	r.w.WriteString("<" + tag)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		r.w.WriteString(" " + attrs[i] + `="`)
+		r.w.Write(util.EscapeHTML([]byte(attrs[i+1])))
+		r.w.WriteByte('"')
+	}
+	if style != "" {
+		r.w.WriteString(` style="`)
+		r.w.Write(util.EscapeHTML([]byte(style)))
+		r.w.WriteByte('"')
+	}
+	r.w.WriteByte('>')
+}
+
+func (r *markdownRenderer) close(tag string) {
+	r.w.WriteString("</" + tag + ">")
+}
+
+// tag 进入节点时输出开始标签，离开时输出结束标签
+func (r *markdownRenderer) tag(tag, element string, entering bool, attrs ...string) {
+	if entering {
+		r.open(tag, element, attrs...)
+	} else {
+		r.close(tag)
+	}
+}
+
+func (r *markdownRenderer) renderNode(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	// HTML中未闭合的 script、textarea 等标签只在所在的块内生效，避免隐藏后续的全部内容
+	if !entering && n.Type() == ast.TypeBlock {
+		defer func() { r.skipTag = "" }()
+	}
+	switch node := n.(type) {
+	case *ast.Document:
+		if !entering {
+			r.renderFootnotes()
+		}
+		r.tag("section", "section", entering)
+	case *ast.Heading:
+		tag := "h" + strconv.Itoa(node.Level)
+		r.tag(tag, tag, entering)
+	case *ast.Paragraph:
+		// 单独成段的图片输出为figure
+		if !isImageParagraph(node) {
+			r.tag("p", "p", entering)
+		}
+	case *ast.Blockquote:
+		r.tag("blockquote", "blockquote", entering)
+	case *ast.CodeBlock, *ast.FencedCodeBlock:
+		if entering {
+			r.renderCodeBlock(n)
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.HTMLBlock, *ast.RawHTML:
+		if entering {
+			r.renderHTML(markdownHTML(n, r.source))
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.List:
+		switch {
+		case !node.IsOrdered():
+			r.tag("ul", "ul", entering)
+		case node.Start > 1:
+			r.tag("ol", "ol", entering, "start", strconv.Itoa(node.Start))
+		default:
+			r.tag("ol", "ol", entering)
+		}
+	case *ast.ListItem:
+		r.tag("li", "li", entering)
+	case *ast.ThematicBreak:
+		if entering {
+			r.open("hr", "hr")
+		}
+	case *ast.AutoLink:
+		if entering {
+			destination := string(node.URL(r.source))
+			r.renderLinkStart(destination)
+			html.DefaultWriter.Write(r.w, node.Label(r.source))
+			r.renderLinkEnd(destination, string(node.Label(r.source)))
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.Link:
+		destination := string(node.Destination)
+		if entering {
+			r.renderLinkStart(destination)
+		} else {
+			r.renderLinkEnd(destination, markdownPlainText(node, r.source))
+		}
+	case *ast.Image:
+		if entering {
+			r.renderImage(node)
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.CodeSpan:
+		if entering {
+			r.open("code", "code")
+			for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+				if t, ok := child.(*ast.Text); ok {
+					r.w.Write(util.EscapeHTML(bytes.TrimSuffix(t.Segment.Value(r.source), []byte("\n"))))
+				}
+			}
+			r.close("code")
+		}
+		return ast.WalkSkipChildren, nil
+	case *ast.Emphasis:
+		if node.Level == 2 {
+			r.tag("strong", "strong", entering)
+		} else {
+			r.tag("em", "em", entering)
+		}
+	case *ast.Text:
+		if entering && r.skipTag == "" {
+			r.renderText(node)
+		}
+	case *ast.String:
+		if entering && r.skipTag == "" {
+			if node.IsCode() || node.IsRaw() {
+				r.w.Write(node.Value)
+			} else {
+				html.DefaultWriter.Write(r.w, node.Value)
+			}
+		}
+	case *extast.Strikethrough:
+		r.tag("del", "del", entering)
+	case *extast.TaskCheckBox:
+		if entering {
+			if node.IsChecked {
+				r.w.WriteString("☑ ")
+			} else {
+				r.w.WriteString("☐ ")
+			}
+		}
+	case *extast.Table:
+		r.tag("table", "table", entering)
+	case *extast.TableHeader, *extast.TableRow:
+		r.tag("tr", "", entering)
+	case *extast.TableCell:
+		r.renderTableCell(node, entering)
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *markdownRenderer) renderText(node *ast.Text) {
+	value := node.Segment.Value(r.source)
+	if node.IsRaw() {
+		html.DefaultWriter.RawWrite(r.w, value)
+	} else {
+		html.DefaultWriter.Write(r.w, value)
+	}
+	switch {
+	case node.HardLineBreak():
+		r.w.WriteString("<br>")
+	case node.SoftLineBreak():
+		r.w.WriteByte('\n')
+	}
+}
+
+// renderCodeBlock 输出代码块，微信会合并连续的空白，换行和空格需要转换为<br>和&nbsp;
+func (r *markdownRenderer) renderCodeBlock(n ast.Node) {
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(r.source))
+	}
+	escaped := strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+		"\t", "&nbsp;&nbsp;&nbsp;&nbsp;", " ", "&nbsp;", "\r", "", "\n", "<br>",
+	).Replace(strings.TrimSuffix(code.String(), "\n"))
+
+	r.open("pre", "pre")
+	r.open("code", "pre code")
+	r.w.WriteString(escaped)
+	r.close("code")
+	r.close("pre")
+}
+
+// renderLinkStart 公众号文章链接输出为<a>，其他链接输出为带样式的文字并在结尾标注参考资料编号
+func (r *markdownRenderer) renderLinkStart(destination string) {
+	if isWeChatArticleLink(destination) {
+		r.open("a", "a", "href", destination)
+		return
+	}
+	r.open("span", "a")
+}
+
+func (r *markdownRenderer) renderLinkEnd(destination, text string) {
+	if isWeChatArticleLink(destination) {
+		r.close("a")
+		return
+	}
+	r.close("span")
+	if destination == "" || strings.HasPrefix(destination, "#") {
+		return
+	}
+	index := -1
+	for i, footnote := range r.footnotes {
+		if footnote.url == destination {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		r.footnotes = append(r.footnotes, markdownFootnote{text: text, url: destination})
+		index = len(r.footnotes) - 1
+	}
+	r.open("sup", "sup")
+	r.w.WriteString("[" + strconv.Itoa(index+1) + "]")
+	r.close("sup")
+}
+
+// renderFootnotes 在文末输出参考资料
+func (r *markdownRenderer) renderFootnotes() {
+	if len(r.footnotes) == 0 {
+		return
+	}
+	r.open("section", "footnotes")
+	r.open("h4", "h4")
+	r.w.WriteString("参考资料")
+	r.close("h4")
+	for i, footnote := range r.footnotes {
+		r.open("p", "footnote")
+		r.w.WriteString("[" + strconv.Itoa(i+1) + "] ")
+		if footnote.text != "" && footnote.text != footnote.url {
+			r.w.Write(util.EscapeHTML([]byte(footnote.text)))
+			r.w.WriteString("：")
+		}
+		r.w.Write(util.EscapeHTML([]byte(footnote.url)))
+		r.close("p")
+	}
+	r.close("section")
+}
+
+// renderImage 输出图片，单独成段且有替代文字的图片会在下方显示图片说明
+func (r *markdownRenderer) renderImage(node *ast.Image) {
+	alt := markdownPlainText(node, r.source)
+	attrs := []string{"src", string(node.Destination), "alt", alt}
+	if node.Title != nil {
+		attrs = append(attrs, "title", string(node.Title))
+	}
+	paragraph, ok := node.Parent().(*ast.Paragraph)
+	if !ok || !isImageParagraph(paragraph) {
+		r.open("img", "img", attrs...)
+		return
+	}
+	r.open("figure", "figure")
+	r.open("img", "img", attrs...)
+	if alt != "" {
+		r.open("figcaption", "figcaption")
+		r.w.Write(util.EscapeHTML([]byte(alt)))
+		r.close("figcaption")
+	}
+	r.close("figure")
+}
+
+func (r *markdownRenderer) renderTableCell(node *extast.TableCell, entering bool) {
+	tag := "td"
+	if node.Parent().Kind() == extast.KindTableHeader {
+		tag = "th"
+	}
+	if !entering {
+		r.close(tag)
+		return
+	}
+	style := r.theme.Styles[tag]
+	if node.Alignment != extast.AlignNone {
+		style = strings.TrimSpace(style + " text-align: " + node.Alignment.String() + ";")
+	}
+	r.openStyled(tag, style)
+}
+
+// isImageParagraph 判断段落是否只包含一张图片
+func isImageParagraph(paragraph *ast.Paragraph) bool {
+	child := paragraph.FirstChild()
+	return child != nil && child.Kind() == ast.KindImage && child.NextSibling() == nil
+}
+
+// markdownPlainText 返回节点中的纯文本
+func markdownPlainText(n ast.Node, source []byte) string {
+	var buf strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := child.(type) {
+		case *ast.Text:
+			buf.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(node.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,186 @@
+package wechat
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"golang.org/x/net/html"
+)
+
+// markdownHTMLTags Markdown中可以保留的HTML标签及其可保留的属性，所有标签均可保留style属性
+// 其他标签会被移除，但保留其中的文字；markdownHTMLSkipTags 中的标签连同内容一起移除
+var markdownHTMLTags = map[string][]string{
+	"p": nil, "br": nil, "span": nil, "section": nil, "div": nil,
+	"strong": nil, "b": nil, "em": nil, "i": nil, "u": nil, "s": nil, "del": nil,
+	"sub": nil, "sup": nil, "code": nil, "pre": nil, "blockquote": nil, "hr": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+	"figure": nil, "figcaption": nil,
+	"img": {"src", "alt", "title", "width", "height"},
+	"a":   {"href"},
+}
+
+// markdownHTMLSkipTags 连同内容一起移除的标签
+var markdownHTMLSkipTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "textarea": true, "template": true, "title": true,
+}
+
+// markdownHTML 返回HTML块或行内HTML节点的原始内容，其他节点返回nil
+func markdownHTML(n ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	switch node := n.(type) {
+	case *ast.HTMLBlock:
+		lines := node.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			buf.Write(line.Value(source))
+		}
+		if node.HasClosure() {
+			buf.Write(node.ClosureLine.Value(source))
+		}
+	case *ast.RawHTML:
+		for i := 0; i < node.Segments.Len(); i++ {
+			segment := node.Segments.At(i)
+			buf.Write(segment.Value(source))
+		}
+	default:
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// htmlImageSources 返回HTML片段中全部<img>的src
+func htmlImageSources(fragment []byte) []string {
+	var sources []string
+	tokenizer := html.NewTokenizer(bytes.NewReader(fragment))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return sources
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "img" {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "src" && attr.Val != "" {
+					sources = append(sources, attr.Val)
+				}
+			}
+		}
+	}
+}
+
+// renderHTML 按白名单输出Markdown中的HTML，图片地址替换为已上传的微信图片地址
+// 行内HTML的开始和结束标签分属不同节点，标签状态保存在渲染器中
+func (r *markdownRenderer) renderHTML(fragment []byte) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(fragment))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return
+		}
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			if r.skipTag == "" {
+				r.w.WriteString(html.EscapeString(token.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if r.skipTag != "" {
+				continue
+			}
+			if markdownHTMLSkipTags[token.Data] {
+				if tokenType == html.StartTagToken {
+					r.skipTag = token.Data
+				}
+				continue
+			}
+			r.renderHTMLStartTag(token)
+		case html.EndTagToken:
+			if r.skipTag != "" {
+				if token.Data == r.skipTag {
+					r.skipTag = ""
+				}
+				continue
+			}
+			r.renderHTMLEndTag(token)
+		}
+	}
+}
+
+func (r *markdownRenderer) renderHTMLStartTag(token html.Token) {
+	allowed, ok := markdownHTMLTags[token.Data]
+	if !ok {
+		return
+	}
+	tag := token.Data
+	var (
+		attrs []string
+		style string
+	)
+	for _, attr := range token.Attr {
+		switch {
+		case attr.Key == "style":
+			style = sanitizeHTMLStyle(attr.Val)
+		case attr.Key == "src" && tag == "img":
+			src, ok := r.images[attr.Val]
+			if !ok && !isWeChatImage(attr.Val) {
+				// 未能上传的图片不输出
+				return
+			}
+			if !ok {
+				src = attr.Val
+			}
+			attrs = append(attrs, "src", src)
+		case attr.Key == "href" && tag == "a":
+			if isWeChatArticleLink(attr.Val) {
+				attrs = append(attrs, "href", attr.Val)
+			}
+		case containsString(allowed, attr.Key):
+			attrs = append(attrs, attr.Key, attr.Val)
+		}
+	}
+	if tag == "img" && len(attrs) == 0 {
+		return
+	}
+	if tag == "a" {
+		// 图文消息中只有公众号文章链接可以点击，其他链接输出为<span>
+		if len(attrs) == 0 {
+			tag = "span"
+		}
+		r.anchors = append(r.anchors, tag)
+	}
+	r.openStyled(tag, style, attrs...)
+}
+
+func (r *markdownRenderer) renderHTMLEndTag(token html.Token) {
+	tag := token.Data
+	if _, ok := markdownHTMLTags[tag]; !ok {
+		return
+	}
+	switch tag {
+	case "br", "hr", "img":
+		return
+	case "a":
+		if len(r.anchors) == 0 {
+			return
+		}
+		tag = r.anchors[len(r.anchors)-1]
+		r.anchors = r.anchors[:len(r.anchors)-1]
+	}
+	r.close(tag)
+}
+
+// sanitizeHTMLStyle 移除可能加载外部资源或执行脚本的样式
+func sanitizeHTMLStyle(style string) string {
+	lower := strings.ToLower(style)
+	if strings.Contains(lower, "url(") || strings.Contains(lower, "expression(") ||
+		strings.Contains(lower, "javascript:") || strings.Contains(lower, "@import") {
+		return ""
+	}
+	return style
+}
@@ -0,0 +1,190 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// markdownServer 模拟图片下载及上传图文消息内图片的接口，上传后的地址为 http://mmbiz.qpic.cn/<序号>
+func markdownServer(t *testing.T, uploads *int32) http.HandlerFunc {
+	image := noisePNG(t, 4, 4)
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/media/uploadimg":
+			if _, _, err := r.FormFile("media"); err != nil {
+				t.Error(err)
+			}
+			fmt.Fprintf(w, `{"url":"http://mmbiz.qpic.cn/%d"}`, atomic.AddInt32(uploads, 1))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+			w.Write(make([]byte, markdownImageMaxSize))
+		case "/slow.png":
+			time.Sleep(200 * time.Millisecond)
+			w.Write(image)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// newTestMarkdownConverter 返回不带样式的转换器，便于比较输出
+func newTestMarkdownConverter(t *testing.T, uploads *int32) *MarkdownConverter {
+	converter := NewMarkdownConverter(newTestSDK(t, markdownServer(t, uploads)))
+	converter.Theme = MarkdownTheme{Styles: map[string]string{"h2": "color: red;"}}
+	return converter
+}
+
+func TestMarkdownConverterConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "标题",
+			source: "## 标题 *强调*\n\n### 三级标题",
+			want:   `<section><h2 style="color: red;">标题 <em>强调</em></h2><h3>三级标题</h3></section>`,
+		},
+		{
+			name:   "代码块",
+			source: "```go\nif a < b {\n\treturn \"x\"\n}\n```",
+			want:   `<section><pre><code>if&nbsp;a&nbsp;&lt;&nbsp;b&nbsp;{<br>&nbsp;&nbsp;&nbsp;&nbsp;return&nbsp;&quot;x&quot;<br>}</code></pre></section>`,
+		},
+		{
+			name:   "行内代码",
+			source: "运行 `go <test>`",
+			want:   `<section><p>运行 <code>go &lt;test&gt;</code></p></section>`,
+		},
+		{
+			name:   "外部链接转为参考资料",
+			source: "见[文档](https://example.com/doc)和[文档](https://example.com/doc)，[文章](https://mp.weixin.qq.com/s/abc)",
+			want: `<section><p>见<span>文档</span><sup>[1]</sup>和<span>文档</span><sup>[1]</sup>，<a href="https://mp.weixin.qq.com/s/abc">文章</a></p>` +
+				`<section><h4>参考资料</h4><p>[1] 文档：https://example.com/doc</p></section></section>`,
+		},
+		{
+			name:   "表格",
+			source: "| 名称 | 数量 |\n| :-- | --: |\n| 苹果 | 1 |",
+			want: `<section><table><tr><th style="text-align: left;">名称</th><th style="text-align: right;">数量</th></tr>` +
+				`<tr><td style="text-align: left;">苹果</td><td style="text-align: right;">1</td></tr></table></section>`,
+		},
+		{
+			name:   "单独成段的图片",
+			source: "![封面](http://example.com/image.png)\n\n文字![](http://mmbiz.qpic.cn/exist)",
+			want: `<section><figure><img src="http://mmbiz.qpic.cn/1" alt="封面"><figcaption>封面</figcaption></figure>` +
+				`<p>文字<img src="http://mmbiz.qpic.cn/exist" alt=""></p></section>`,
+		},
+		{
+			name:   "任务列表及删除线",
+			source: "- [x] ~~完成~~\n- [ ] 待办",
+			want:   `<section><ul><li>☑ <del>完成</del></li><li>☐ 待办</li></ul></section>`,
+		},
+		{
+			name: "HTML块",
+			source: "<div onclick=\"alert(1)\" style=\"color: red;\"><img src=\"http://example.com/image.png\" onerror=\"x\">" +
+				"<a href=\"https://example.com\">外链</a><iframe src=\"https://example.com\"></iframe></div>\n\n" +
+				"<script>\nalert(1)\n</script>",
+			want: `<section><div style="color: red;"><img src="http://mmbiz.qpic.cn/1"><span>外链</span></div>` + "\n" + `</section>`,
+		},
+		{
+			name:   "行内HTML",
+			source: `文字<span style="background: url(x)" class="a">高亮</span><script>alert(1)</script><u>下划线</u><br/>`,
+			want:   `<section><p>文字<span>高亮</span><u>下划线</u><br></p></section>`,
+		},
+		{
+			name:   "未闭合的移除标签只影响所在的块",
+			source: "<div><style>\n\n正文\n\n文字<textarea>隐藏\n\n后续段落",
+			want:   `<section><div><p>正文</p><p>文字</p><p>后续段落</p></section>`,
+		},
+		{
+			name: "伪装成公众号域名的脚本链接",
+			source: "[链接](javascript://mp.weixin.qq.com/%0aalert(1))\n\n" +
+				"<a href=\"javascript://mp.weixin.qq.com/%0aalert(1)\">链接</a>",
+			want: `<section><p><span>链接</span><sup>[1]</sup></p><p><span>链接</span></p>` +
+				`<section><h4>参考资料</h4><p>[1] 链接：javascript://mp.weixin.qq.com/%0aalert(1)</p></section></section>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploads int32
+			converter := newTestMarkdownConverter(t, &uploads)
+			got, err := converter.Convert(context.Background(), []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Convert() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeChatURLs(t *testing.T) {
+	tests := []struct {
+		url     string
+		article bool
+		image   bool
+	}{
+		{"https://mp.weixin.qq.com/s/abc", true, false},
+		{"HTTP://mp.weixin.qq.com/s/abc", true, false},
+		{"javascript://mp.weixin.qq.com/%0aalert(1)", false, false},
+		{"//mp.weixin.qq.com/s/abc", false, false},
+		{"http://mmbiz.qpic.cn/mmbiz_png/1", false, true},
+		{"javascript://mmbiz.qpic.cn/%0aalert(1)", false, false},
+		{"data://mmbiz.qpic.cn/x", false, false},
+	}
+	for _, tt := range tests {
+		if got := isWeChatArticleLink(tt.url); got != tt.article {
+			t.Errorf("isWeChatArticleLink(%q) = %v", tt.url, got)
+		}
+		if got := isWeChatImage(tt.url); got != tt.image {
+			t.Errorf("isWeChatImage(%q) = %v", tt.url, got)
+		}
+	}
+}
+
+func TestMarkdownConverterConvertArticle(t *testing.T) {
+	var uploads int32
+	converter := newTestMarkdownConverter(t, &uploads)
+	source := "# 标题\n\n![](http://example.com/image.png)\n\n<img src=\"http://example.com/image.png\">"
+	article, err := converter.ConvertArticle(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "标题" || strings.Contains(article.Content, "<h1") {
+		t.Fatalf("ConvertArticle() = %+v", article)
+	}
+	// 相同的图片只上传一次
+	if uploads != 1 || strings.Count(article.Content, "http://mmbiz.qpic.cn/1") != 2 {
+		t.Fatalf("uploaded %d times, content %s", uploads, article.Content)
+	}
+}
+
+func TestMarkdownConverterRemoteImageLimits(t *testing.T) {
+	var uploads int32
+	converter := newTestMarkdownConverter(t, &uploads)
+
+	_, err := converter.Convert(context.Background(), []byte("![](http://example.com/huge.png)"))
+	if !errors.Is(err, ErrMediaTooLarge) {
+		t.Fatalf("Convert() error = %v, want ErrMediaTooLarge", err)
+	}
+	converter.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+	if _, err = converter.Convert(context.Background(), []byte("![](http://example.com/slow.png)")); err == nil {
+		t.Fatal("Convert() error = nil, want timeout")
+	}
+	if _, err = converter.Convert(context.Background(), []byte("![](http://example.com/missing.png)")); err == nil {
+		t.Fatal("Convert() error = nil, want status error")
+	}
+	if uploads != 0 {
+		t.Fatalf("uploaded %d times, want 0", uploads)
+	}
+}
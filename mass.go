@@ -0,0 +1,303 @@
+package wechat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 群发消息类型
+const (
+	MassText    = "text"    // 文本
+	MassImage   = "image"   // 图片
+	MassVoice   = "voice"   // 语音
+	MassMpVideo = "mpvideo" // 视频
+	MassMpNews  = "mpnews"  // 图文
+)
+
+// 根据openid列表群发时单次请求的openid数量范围
+const (
+	massOpenIDMin = 2
+	massOpenIDMax = 10000
+)
+
+// MassMessage 群发消息内容，建议使用 NewMassText 等方法构造
+type MassMessage struct {
+	MsgType            string   // 群发消息类型
+	Content            string   // 文本消息内容
+	MediaID            string   // 语音、视频、图文消息的media_id
+	MediaIDs           []string // 图片消息的media_id列表
+	Recommend          string   // 图片消息的推荐语
+	NeedOpenComment    int      // 图片消息是否打开评论，0不打开，1打开
+	OnlyFansCanComment int      // 图片消息是否粉丝才可评论，0所有人可评论，1粉丝才可评论
+	Title              string   // 视频消息标题，仅根据openid列表群发时有效
+	Description        string   // 视频消息描述，仅根据openid列表群发时有效
+	SendIgnoreReprint  int      // 图文消息被判定为转载时是否继续群发，1继续群发，0停止群发
+	ClientMsgID        string   // 群发接口防重入id，最长64个字符
+}
+
+// NewMassText 构造文本群发消息
+func NewMassText(content string) *MassMessage {
+	return &MassMessage{MsgType: MassText, Content: content}
+}
+
+// NewMassImage 构造图片群发消息，mediaIDs为图片永久素材的media_id
+func NewMassImage(mediaIDs ...string) *MassMessage {
+	return &MassMessage{MsgType: MassImage, MediaIDs: mediaIDs}
+}
+
+// NewMassVoice 构造语音群发消息
+func NewMassVoice(mediaID string) *MassMessage {
+	return &MassMessage{MsgType: MassVoice, MediaID: mediaID}
+}
+
+// NewMassVideo 构造视频群发消息
+func NewMassVideo(mediaID, title, description string) *MassMessage {
+	return &MassMessage{MsgType: MassMpVideo, MediaID: mediaID, Title: title, Description: description}
+}
+
+// NewMassNews 构造图文群发消息，sendIgnoreReprint为true时被判定为转载仍继续群发
+func NewMassNews(mediaID string, sendIgnoreReprint bool) *MassMessage {
+	message := &MassMessage{MsgType: MassMpNews, MediaID: mediaID}
+	if sendIgnoreReprint {
+		message.SendIgnoreReprint = 1
+	}
+	return message
+}
+
+// body 构造群发接口的请求数据
+func (m *MassMessage) body() (map[string]interface{}, error) {
+	data := map[string]interface{}{
+		"msgtype": m.MsgType,
+	}
+	switch m.MsgType {
+	case MassText:
+		data["text"] = map[string]interface{}{"content": m.Content}
+	case MassImage:
+		data["images"] = map[string]interface{}{
+			"media_ids":             m.MediaIDs,
+			"recommend":             m.Recommend,
+			"need_open_comment":     m.NeedOpenComment,
+			"only_fans_can_comment": m.OnlyFansCanComment,
+		}
+	case MassVoice:
+		data["voice"] = map[string]interface{}{"media_id": m.MediaID}
+	case MassMpVideo:
+		video := map[string]interface{}{"media_id": m.MediaID}
+		if m.Title != "" {
+			video["title"] = m.Title
+		}
+		if m.Description != "" {
+			video["description"] = m.Description
+		}
+		data["mpvideo"] = video
+	case MassMpNews:
+		data["mpnews"] = map[string]interface{}{"media_id": m.MediaID}
+		data["send_ignore_reprint"] = m.SendIgnoreReprint
+	default:
+		return nil, fmt.Errorf("不支持的群发消息类型：%s", m.MsgType)
+	}
+	if m.ClientMsgID != "" {
+		data["clientmsgid"] = m.ClientMsgID
+	}
+	return data, nil
+}
+
+// SendMassToAll 群发消息给全部用户
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) SendMassToAll(message *MassMessage) (*MassSendResponse, error) {
+	return s.sendMassAll(map[string]interface{}{"is_to_all": true}, message)
+}
+
+// SendMassByTag 根据标签群发消息
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) SendMassByTag(tagID int, message *MassMessage) (*MassSendResponse, error) {
+	return s.sendMassAll(map[string]interface{}{"is_to_all": false, "tag_id": tagID}, message)
+}
+
+func (s *SDK) sendMassAll(filter map[string]interface{}, message *MassMessage) (*MassSendResponse, error) {
+	data, err := message.body()
+	if err != nil {
+		return nil, err
+	}
+	data["filter"] = filter
+
+	if err = s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/sendall?access_token=%s", s.AccessToken)
+
+	var responseJson MassSendResponse
+	if err = postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrSendMassMessage, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// SendMassByOpenIDs 根据openid列表群发消息
+// 接口单次最多支持10000个openid、最少2个，超出部分会自动分批发送，每批返回一个发送结果
+// 设置了ClientMsgID时，各批次会追加序号后缀以避免被防重入机制过滤
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) SendMassByOpenIDs(openIDs []string, message *MassMessage) ([]MassSendResponse, error) {
+	if len(openIDs) < massOpenIDMin {
+		return nil, fmt.Errorf("根据openid列表群发至少需要%d个用户", massOpenIDMin)
+	}
+	data, err := message.body()
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := chunkMassOpenIDs(openIDs)
+	results := make([]MassSendResponse, 0, len(chunks))
+	for i, chunk := range chunks {
+		data["touser"] = chunk
+		if message.ClientMsgID != "" && len(chunks) > 1 {
+			data["clientmsgid"] = fmt.Sprintf("%s_%d", message.ClientMsgID, i)
+		}
+
+		if err = s.checkAccessToken(); err != nil {
+			return results, err
+		}
+		url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/send?access_token=%s", s.AccessToken)
+
+		var responseJson MassSendResponse
+		if err = postJSON(url, data, &responseJson); err != nil {
+			return results, err
+		}
+		if responseJson.Errcode != 0 {
+			return results, ErrorHandler(ErrSendMassMessage, responseJson.Errmsg, responseJson.Errcode)
+		}
+		results = append(results, responseJson)
+	}
+	return results, nil
+}
+
+// chunkMassOpenIDs 按接口限制切分openid列表，保证每批不少于2个
+func chunkMassOpenIDs(openIDs []string) [][]string {
+	chunks := chunkOpenIDs(openIDs, massOpenIDMax)
+	// 最后一批只有1个用户时，从上一批借一个
+	if n := len(chunks); n > 1 && len(chunks[n-1]) < massOpenIDMin {
+		prev := chunks[n-2]
+		chunks[n-2] = prev[:len(prev)-1]
+		chunks[n-1] = openIDs[len(openIDs)-massOpenIDMin:]
+	}
+	return chunks
+}
+
+// PreviewMass 预览群发消息，发送给指定openid的用户
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) PreviewMass(openID string, message *MassMessage) (*MassSendResponse, error) {
+	return s.previewMass("touser", openID, message)
+}
+
+// PreviewMassByWxName 预览群发消息，发送给指定微信号的用户
+func (s *SDK) PreviewMassByWxName(wxName string, message *MassMessage) (*MassSendResponse, error) {
+	return s.previewMass("towxname", wxName, message)
+}
+
+func (s *SDK) previewMass(field, to string, message *MassMessage) (*MassSendResponse, error) {
+	if to == "" {
+		return nil, errors.New("预览接收者不能为空")
+	}
+	data, err := message.body()
+	if err != nil {
+		return nil, err
+	}
+	data[field] = to
+
+	if err = s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/preview?access_token=%s", s.AccessToken)
+
+	var responseJson MassSendResponse
+	if err = postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrPreviewMassMessage, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// GetMassStatus 查询群发消息发送状态
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) GetMassStatus(msgID int64) (*GetMassStatusResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/get?access_token=%s", s.AccessToken)
+
+	var responseJson GetMassStatusResponse
+	if err := postJSON(url, map[string]interface{}{"msg_id": msgID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetMassStatus, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// DeleteMass 删除群发消息，articleIdx为要删除的文章在图文消息中的位置（从1开始），为0时删除全部文章
+// 只有已经发送成功的消息才能删除，删除后只会将图文详情页失效，已收到的用户仍能在本地看到消息卡片
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) DeleteMass(msgID int64, articleIdx int) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"msg_id":      msgID,
+		"article_idx": articleIdx,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/delete?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDeleteMassMessage, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// GetMassSpeed 获取群发速度
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) GetMassSpeed() (*MassSpeed, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/speed/get?access_token=%s", s.AccessToken)
+
+	var responseJson MassSpeed
+	if err := postJSON(url, map[string]interface{}{}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetMassSpeed, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// SetMassSpeed 设置群发速度，speed为0-4，0最快（8万/分钟），4最慢（1万/分钟）
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html
+func (s *SDK) SetMassSpeed(speed int) error {
+	if speed < 0 || speed > 4 {
+		return fmt.Errorf("群发速度级别取值范围为0-4，当前为%d", speed)
+	}
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/mass/speed/set?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, map[string]interface{}{"speed": speed}, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrSetMassSpeed, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// openIDList 生成n个openid
+func openIDList(n int) []string {
+	openIDs := make([]string, n)
+	for i := range openIDs {
+		openIDs[i] = fmt.Sprintf("openid-%05d", i)
+	}
+	return openIDs
+}
+
+func TestChunkMassOpenIDs(t *testing.T) {
+	tests := []struct {
+		total int
+		want  []int
+	}{
+		{2, []int{2}},
+		{10000, []int{10000}},
+		{10001, []int{9999, 2}},
+		{10002, []int{10000, 2}},
+		{20001, []int{10000, 9999, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.total), func(t *testing.T) {
+			openIDs := openIDList(tt.total)
+			chunks := chunkMassOpenIDs(openIDs)
+			var sizes []int
+			var joined []string
+			for _, chunk := range chunks {
+				sizes = append(sizes, len(chunk))
+				joined = append(joined, chunk...)
+			}
+			if !reflect.DeepEqual(sizes, tt.want) {
+				t.Fatalf("chunk sizes = %v, want %v", sizes, tt.want)
+			}
+			if !reflect.DeepEqual(joined, openIDs) {
+				t.Fatal("chunks do not cover the openids in order")
+			}
+		})
+	}
+}
+
+func TestSendMassByOpenIDs(t *testing.T) {
+	type request struct {
+		ToUser      []string               `json:"touser"`
+		MsgType     string                 `json:"msgtype"`
+		Text        map[string]interface{} `json:"text"`
+		ClientMsgID string                 `json:"clientmsgid"`
+	}
+	var requests []request
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cgi-bin/message/mass/send" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		var body request
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, body)
+		fmt.Fprintf(w, `{"errcode":0,"errmsg":"send job submission success","msg_id":%d}`, len(requests))
+	})
+
+	message := NewMassText("你好")
+	message.ClientMsgID = "release"
+	results, err := sdk.SendMassByOpenIDs(openIDList(10001), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].MsgID != 2 {
+		t.Fatalf("SendMassByOpenIDs() = %+v", results)
+	}
+	for i, want := range []string{"release_0", "release_1"} {
+		if requests[i].ClientMsgID != want || requests[i].MsgType != MassText || requests[i].Text["content"] != "你好" {
+			t.Fatalf("requests[%d] = %+v", i, requests[i])
+		}
+	}
+	if len(requests[0].ToUser) != 9999 || len(requests[1].ToUser) != 2 {
+		t.Fatalf("touser sizes = %d, %d", len(requests[0].ToUser), len(requests[1].ToUser))
+	}
+
+	if _, err = sdk.SendMassByOpenIDs(openIDList(1), message); err == nil {
+		t.Fatal("SendMassByOpenIDs() with 1 openid error = nil")
+	}
+	if _, err = sdk.SendMassByOpenIDs(openIDList(2), &MassMessage{MsgType: "unknown"}); err == nil {
+		t.Fatal("SendMassByOpenIDs() with unknown type error = nil")
+	}
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(requests))
+	}
+}
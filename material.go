@@ -0,0 +1,229 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// batchGetMaterialLimit 获取素材列表时单次请求的素材数量上限
+const batchGetMaterialLimit = 20
+
+// UploadMaterial 新增永久素材，filename需包含扩展名，contentType为空时根据扩展名推断
+// 图片素材返回的URL可用于图文消息正文；视频素材需要提交描述，请使用 UploadVideoMaterial
+// 上传前会根据文件头校验格式、大小及语音时长，可通过 WithAutoResize 自动缩小超出大小的图片
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html
+func (s *SDK) UploadMaterial(mediaType, filename, contentType string, reader io.Reader, opts ...UploadOption) (*AddMediaResponse, error) {
+	file := multipartFile{field: "media", filename: filename, contentType: contentType, reader: reader, size: readerSize(reader)}
+	return s.addMaterial(mediaType, file, nil, opts)
+}
+
+// UploadVideoMaterial 新增永久视频素材，需同时提交视频的标题和描述
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html
+func (s *SDK) UploadVideoMaterial(filename, contentType string, reader io.Reader, description VideoDescription, opts ...UploadOption) (*AddMediaResponse, error) {
+	data, err := json.Marshal(description)
+	if err != nil {
+		return nil, err
+	}
+	file := multipartFile{field: "media", filename: filename, contentType: contentType, reader: reader, size: readerSize(reader)}
+	return s.addMaterial(MediaTypeVideo, file, map[string]string{"description": string(data)}, opts)
+}
+
+// UploadArticleImage 上传图文消息内的图片，返回可用于正文的图片URL，图片仅支持JPG、PNG格式，大小在1MB以下
+// 上传的图片不占用素材库的数量限制，可通过 WithAutoResize 自动缩小超出大小的图片
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html
+func (s *SDK) UploadArticleImage(filename, contentType string, reader io.Reader, opts ...UploadOption) (string, error) {
+	o := newUploadOptions(opts)
+	file := multipartFile{field: "media", filename: filename, contentType: contentType, reader: reader, size: readerSize(reader)}
+	file, err := prepareMedia(mediaTypeArticleImage, file, o)
+	if err != nil {
+		return "", err
+	}
+	if err = s.checkAccessToken(); err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/media/uploadimg?access_token=%s", s.AccessToken)
+
+	var responseJson UploadArticleImageResponse
+	if err = postMultipart(url, file, nil, o, &responseJson); err != nil {
+		return "", err
+	}
+	if responseJson.Errcode != 0 {
+		return "", ErrorHandler(ErrUploadArticleImage, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.URL, nil
+}
+
+func (s *SDK) addMaterial(mediaType string, file multipartFile, fields map[string]string, opts []UploadOption) (*AddMediaResponse, error) {
+	o := newUploadOptions(opts)
+	file, err := prepareMedia(mediaType, file, o)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/material/add_material?access_token=%s&type=%s",
+		s.AccessToken, mediaType)
+
+	var responseJson AddMediaResponse
+	if err = postMultipart(url, file, fields, o, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrAddMaterial, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// Material 获取到的永久素材
+type Material struct {
+	MediaFile                      // 图片、语音等文件素材的信息，内容已写入w
+	Title       string             // 视频素材的标题
+	Description string             // 视频素材的描述
+	DownURL     string             // 视频素材的下载地址
+	NewsItem    []MaterialNewsItem // 图文素材的内容
+}
+
+// GetMaterial 获取永久素材，图片、语音等文件素材的内容写入w；视频和图文素材只返回信息，不写入w
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Getting_Permanent_Assets.html
+func (s *SDK) GetMaterial(mediaID string, w io.Writer) (*Material, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/material/get_material?access_token=%s", s.AccessToken)
+	resp, err := postJSONResponse(url, map[string]interface{}{"media_id": mediaID})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if isJSONResponse(resp.Header.Get("Content-Type")) {
+		var responseJson struct {
+			Title       string             `json:"title"`
+			Description string             `json:"description"`
+			DownURL     string             `json:"down_url"`
+			NewsItem    []MaterialNewsItem `json:"news_item"`
+			Error
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&responseJson); err != nil {
+			return nil, err
+		}
+		if responseJson.Errcode != 0 {
+			return nil, ErrorHandler(ErrGetMaterial, responseJson.Errmsg, responseJson.Errcode)
+		}
+		return &Material{
+			Title:       responseJson.Title,
+			Description: responseJson.Description,
+			DownURL:     responseJson.DownURL,
+			NewsItem:    responseJson.NewsItem,
+		}, nil
+	}
+	file, err := copyMediaFile(resp, ErrGetMaterial, w)
+	if err != nil {
+		return nil, err
+	}
+	return &Material{MediaFile: *file}, nil
+}
+
+// DelMaterial 删除永久素材
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Deleting_Permanent_Assets.html
+func (s *SDK) DelMaterial(mediaID string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/material/del_material?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, map[string]interface{}{"media_id": mediaID}, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDelMaterial, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// GetMaterialCount 获取永久素材的总数
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_the_total_of_all_materials.html
+func (s *SDK) GetMaterialCount() (*GetMaterialCountResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/material/get_materialcount?access_token=%s", s.AccessToken)
+
+	var responseJson GetMaterialCountResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetMaterialCount, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// BatchGetMaterial 分类型获取永久素材的列表，offset从0开始，count取值在1到20之间
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_materials_list.html
+func (s *SDK) BatchGetMaterial(mediaType string, offset, count int) (*BatchGetMaterialResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"type":   mediaType,
+		"offset": offset,
+		"count":  count,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/material/batchget_material?access_token=%s", s.AccessToken)
+
+	var responseJson BatchGetMaterialResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrBatchGetMaterial, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// MaterialIterator 基于offset分页的永久素材迭代器，每次拉取20个
+//
+//	it := sdk.Materials(ctx, wechat.MediaTypeImage, 0)
+//	for it.Next() {
+//		fmt.Println(it.Item().MediaID)
+//	}
+//	if err := it.Err(); err != nil {
+//		// 可保存 it.Offset() 以便稍后继续
+//	}
+type MaterialIterator struct {
+	offsetPager
+	fetch func(offset int) (*BatchGetMaterialResponse, error)
+	page  []MaterialItem
+}
+
+// Materials 返回指定类型永久素材的迭代器，自动处理分页，offset为起始位置
+func (s *SDK) Materials(ctx context.Context, mediaType string, offset int) *MaterialIterator {
+	return &MaterialIterator{
+		offsetPager: newOffsetPager(ctx, offset),
+		fetch: func(offset int) (*BatchGetMaterialResponse, error) {
+			return s.BatchGetMaterial(mediaType, offset, batchGetMaterialLimit)
+		},
+	}
+}
+
+// Next 移动到下一个素材，没有更多数据、出错或ctx被取消时返回false
+func (it *MaterialIterator) Next() bool {
+	return it.next(func(offset int) (int, int, error) {
+		resp, err := it.fetch(offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		it.page = resp.Item
+		return len(resp.Item), resp.TotalCount, nil
+	})
+}
+
+// Item 返回当前的素材
+func (it *MaterialIterator) Item() *MaterialItem {
+	return &it.page[it.index-1]
+}
@@ -0,0 +1,99 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMaterials(t *testing.T) {
+	const total = 25
+	var requests []string
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var data struct {
+			Type   string `json:"type"`
+			Offset int    `json:"offset"`
+			Count  int    `json:"count"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Error(err)
+			return
+		}
+		requests = append(requests, fmt.Sprintf("%s %d %d", data.Type, data.Offset, data.Count))
+		if data.Offset >= 20 && len(requests) == 2 {
+			w.Write([]byte(`{"errcode":45009,"errmsg":"reach max api daily quota limit"}`))
+			return
+		}
+		resp := BatchGetMaterialResponse{TotalCount: total}
+		for i := data.Offset; i < total && i < data.Offset+data.Count; i++ {
+			resp.Item = append(resp.Item, MaterialItem{MediaID: fmt.Sprintf("media-%d", i)})
+		}
+		resp.ItemCount = len(resp.Item)
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	var mediaIDs []string
+	it := sdk.Materials(context.Background(), MediaTypeImage, 0)
+	for it.Next() {
+		mediaIDs = append(mediaIDs, it.Item().MediaID)
+	}
+	var apiErr *APIError
+	if !errors.As(it.Err(), &apiErr) || apiErr.Errcode != 45009 || it.Offset() != 20 || len(mediaIDs) != 20 {
+		t.Fatalf("got %d materials, offset %d, err %v", len(mediaIDs), it.Offset(), it.Err())
+	}
+
+	// 从出错时的offset继续
+	it = sdk.Materials(context.Background(), MediaTypeImage, it.Offset())
+	for it.Next() {
+		mediaIDs = append(mediaIDs, it.Item().MediaID)
+	}
+	if it.Err() != nil || len(mediaIDs) != total || mediaIDs[total-1] != "media-24" {
+		t.Fatalf("got %d materials, err %v", len(mediaIDs), it.Err())
+	}
+	want := []string{"image 0 20", "image 20 20", "image 20 20"}
+	if fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+}
+
+func TestGetMaterial(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var data struct {
+			MediaID string `json:"media_id"`
+		}
+		if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&data) != nil {
+			t.Errorf("unexpected request body")
+		}
+		switch data.MediaID {
+		case "image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Disposition", `attachment; filename="cover.png"`)
+			w.Write([]byte("png"))
+		case "video":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"title":"标题","description":"描述","down_url":"http://example.com/video.mp4"}`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"errcode":40007,"errmsg":"invalid media_id"}`))
+		}
+	})
+
+	var buf bytes.Buffer
+	material, err := sdk.GetMaterial("image", &buf)
+	if err != nil || buf.String() != "png" || material.Filename != "cover.png" || material.Size != 3 {
+		t.Fatalf("GetMaterial(image) = %+v, %v", material, err)
+	}
+	buf.Reset()
+	material, err = sdk.GetMaterial("video", &buf)
+	if err != nil || buf.Len() != 0 || material.Title != "标题" || material.DownURL != "http://example.com/video.mp4" {
+		t.Fatalf("GetMaterial(video) = %+v, %v", material, err)
+	}
+	var apiErr *APIError
+	if _, err = sdk.GetMaterial("missing", &buf); !errors.As(err, &apiErr) || apiErr.Errcode != 40007 {
+		t.Fatalf("GetMaterial(missing) error = %v", err)
+	}
+}
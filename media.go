@@ -0,0 +1,209 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// tempMediaLifetime 临时素材在微信后台的保存时间
+const tempMediaLifetime = 3 * 24 * time.Hour
+
+// TempMedia 临时素材
+type TempMedia struct {
+	Type      string    // 素材类型，image、voice、video、thumb
+	MediaID   string    // 素材ID，缩略图为 thumb_media_id
+	CreatedAt time.Time // 上传时间
+}
+
+// ExpiresAt 返回临时素材的过期时间，临时素材在微信后台保存3天
+func (m *TempMedia) ExpiresAt() time.Time {
+	return m.CreatedAt.Add(tempMediaLifetime)
+}
+
+// UploadTempMedia 新增临时素材，filename需包含扩展名，contentType为空时根据扩展名推断
+// 图片（image）10M，支持PNG、JPEG、JPG、GIF格式；语音（voice）2M，播放长度不超过60s，支持AMR、MP3格式
+// 视频（video）10MB，支持MP4格式；缩略图（thumb）支持JPG格式
+// 上传前会根据文件头校验格式、大小及语音时长，可通过 WithAutoResize 自动缩小超出大小的图片
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/New_temporary_materials.html
+func (s *SDK) UploadTempMedia(mediaType, filename, contentType string, reader io.Reader, opts ...UploadOption) (*TempMedia, error) {
+	o := newUploadOptions(opts)
+	file := multipartFile{field: "media", filename: filename, contentType: contentType, reader: reader, size: readerSize(reader)}
+	file, err := prepareMedia(mediaType, file, o)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/media/upload?access_token=%s&type=%s",
+		s.AccessToken, mediaType)
+
+	var responseJson UploadTempMediaResponse
+	if err = postMultipart(url, file, nil, o, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrUploadTempMedia, responseJson.Errmsg, responseJson.Errcode)
+	}
+
+	media := &TempMedia{
+		Type:      responseJson.Type,
+		MediaID:   responseJson.MediaID,
+		CreatedAt: time.Unix(responseJson.CreatedAt, 0),
+	}
+	if media.MediaID == "" {
+		media.MediaID = responseJson.ThumbMediaID
+	}
+	return media, nil
+}
+
+// UploadTempMediaFile 上传本地文件为临时素材，使用文件名推断格式
+func (s *SDK) UploadTempMediaFile(mediaType, filePath string, opts ...UploadOption) (*TempMedia, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return s.UploadTempMedia(mediaType, filepath.Base(filePath), "", file, opts...)
+}
+
+// mediaExtensions 常见素材格式对应的扩展名
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/bmp":  ".bmp",
+	"audio/amr":  ".amr",
+	"audio/mpeg": ".mp3",
+	"audio/mp3":  ".mp3",
+	"video/mp4":  ".mp4",
+}
+
+// filenameFromURL 从下载地址中获取文件名，地址中没有扩展名时根据contentType补充
+func filenameFromURL(rawURL, contentType string) string {
+	name := "media"
+	if u, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	if path.Ext(name) != "" {
+		return name
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return name
+	}
+	if ext, ok := mediaExtensions[mediaType]; ok {
+		return name + ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return name + exts[0]
+	}
+	return name
+}
+
+// MediaFile 下载的素材信息
+type MediaFile struct {
+	Filename    string // 文件名，来自响应头 Content-Disposition
+	ContentType string // 文件类型，来自响应头 Content-Type
+	Size        int64  // 写入的字节数
+	VideoURL    string // 视频素材的下载地址，仅视频素材返回
+}
+
+// DownloadMedia 获取临时素材，将内容写入w；视频素材会从返回的 video_url 下载
+// 接口返回错误时不会向w写入任何内容，错误为 *APIError
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_temporary_materials.html
+func (s *SDK) DownloadMedia(mediaID string, w io.Writer) (*MediaFile, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/media/get?access_token=%s&media_id=%s",
+		s.AccessToken, mediaID)
+	return downloadMedia(url, ErrDownloadMedia, w)
+}
+
+// DownloadJSSDKVoice 获取通过JSSDK上传的高清语音素材，格式为speex，16K采样率，将内容写入w
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_temporary_materials.html
+func (s *SDK) DownloadJSSDKVoice(mediaID string, w io.Writer) (*MediaFile, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/media/get/jssdk?access_token=%s&media_id=%s",
+		s.AccessToken, mediaID)
+	return downloadMedia(url, ErrDownloadJSSDKVoice, w)
+}
+
+// downloadMedia 下载素材，响应为JSON时解析其中的错误或视频下载地址，视频下载地址只跟随一次
+func downloadMedia(url, errAction string, w io.Writer) (*MediaFile, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !isJSONResponse(resp.Header.Get("Content-Type")) {
+		return copyMediaFile(resp, errAction, w)
+	}
+	var responseJson struct {
+		VideoURL string `json:"video_url"`
+		Error
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(errAction, responseJson.Errmsg, responseJson.Errcode)
+	}
+	if responseJson.VideoURL == "" {
+		return nil, fmt.Errorf("%s:响应中没有素材内容", errAction)
+	}
+
+	videoResp, err := http.Get(responseJson.VideoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer videoResp.Body.Close()
+	if videoResp.StatusCode == http.StatusOK && isJSONResponse(videoResp.Header.Get("Content-Type")) {
+		return nil, fmt.Errorf("%s:视频下载地址返回了JSON响应", errAction)
+	}
+	file, err := copyMediaFile(videoResp, errAction, w)
+	if err != nil {
+		return nil, err
+	}
+	file.VideoURL = responseJson.VideoURL
+	return file, nil
+}
+
+// copyMediaFile 将素材内容写入w，并从响应头中获取文件名和文件类型
+func copyMediaFile(resp *http.Response, errAction string, w io.Writer) (*MediaFile, error) {
+	// 非200时错误码为HTTP状态码
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrorHandler(errAction, http.StatusText(resp.StatusCode), resp.StatusCode)
+	}
+
+	file := &MediaFile{ContentType: resp.Header.Get("Content-Type")}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		file.Filename = params["filename"]
+	}
+	var err error
+	file.Size, err = io.Copy(w, resp.Body)
+	return file, err
+}
+
+// isJSONResponse 判断响应是否为JSON，微信接口出错时可能返回 application/json 或 text/plain
+func isJSONResponse(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/plain"
+}
@@ -0,0 +1,274 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrMediaNotCached 缓存中不存在该素材
+var ErrMediaNotCached = errors.New("素材未缓存")
+
+// CachedMedia 已上传的素材
+type CachedMedia struct {
+	Hash      string    // 素材内容的SHA-256
+	MediaType string    // 素材类型
+	Permanent bool      // 是否为永久素材
+	MediaID   string    // 素材ID
+	URL       string    // 永久图片素材的URL
+	CreatedAt time.Time // 上传时间
+}
+
+// ExpiresAt 返回素材的过期时间，永久素材返回零值
+func (m *CachedMedia) ExpiresAt() time.Time {
+	if m.Permanent {
+		return time.Time{}
+	}
+	return m.CreatedAt.Add(tempMediaLifetime)
+}
+
+// mediaCacheKey 素材在存储中的键，区分素材类型及临时、永久素材
+func mediaCacheKey(mediaType string, permanent bool, hash string) string {
+	kind := "temp"
+	if permanent {
+		kind = "permanent"
+	}
+	return mediaType + ":" + kind + ":" + hash
+}
+
+// MediaCacheStore 素材缓存的存储，可自行实现以对接Redis、数据库等
+type MediaCacheStore interface {
+	// Get 读取素材，不存在时返回 ErrMediaNotCached
+	Get(key string) (*CachedMedia, error)
+	// Put 保存素材
+	Put(key string, media CachedMedia) error
+	// Delete 删除素材
+	Delete(key string) error
+}
+
+// MemoryMediaCacheStore 基于内存的素材缓存存储
+type MemoryMediaCacheStore struct {
+	mutex sync.RWMutex
+	media map[string]CachedMedia
+}
+
+// NewMemoryMediaCacheStore 实例化内存素材缓存存储
+func NewMemoryMediaCacheStore() *MemoryMediaCacheStore {
+	return &MemoryMediaCacheStore{media: make(map[string]CachedMedia)}
+}
+
+func (m *MemoryMediaCacheStore) Get(key string) (*CachedMedia, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	media, ok := m.media[key]
+	if !ok {
+		return nil, ErrMediaNotCached
+	}
+	return &media, nil
+}
+
+func (m *MemoryMediaCacheStore) Put(key string, media CachedMedia) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.media[key] = media
+	return nil
+}
+
+func (m *MemoryMediaCacheStore) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.media, key)
+	return nil
+}
+
+// MediaCache 按内容哈希缓存已上传的素材，相同内容只上传一次
+// 临时素材在过期前 ExpiryMargin 视为失效并重新上传
+type MediaCache struct {
+	MediaType        string           // 素材类型，为空时根据文件头推断为 image、voice 或 video
+	Permanent        bool             // 是否上传为永久素材
+	VideoDescription VideoDescription // 上传永久视频素材时的描述
+	ExpiryMargin     time.Duration    // 临时素材提前失效的时长，默认1小时
+	UploadOptions    []UploadOption   // 上传素材的选项
+
+	sdk      *SDK
+	store    MediaCacheStore
+	mutex    sync.Mutex
+	inflight map[string]*mediaUpload // 正在上传的素材，相同内容并发调用时只上传一次
+}
+
+// mediaUpload 正在进行的上传
+type mediaUpload struct {
+	done  chan struct{}
+	media *CachedMedia
+	err   error
+}
+
+// NewMediaCache 实例化素材缓存，store为空时使用内存存储
+func NewMediaCache(sdk *SDK, store MediaCacheStore) *MediaCache {
+	if store == nil {
+		store = NewMemoryMediaCacheStore()
+	}
+	return &MediaCache{
+		ExpiryMargin: time.Hour,
+		sdk:          sdk,
+		store:        store,
+		inflight:     make(map[string]*mediaUpload),
+	}
+}
+
+// EnsureMedia 返回reader内容对应的有效素材，缓存中没有或临时素材即将过期时才上传
+// reader实现 io.Seeker 时先计算哈希再从头上传，否则会读取全部内容到内存
+func (c *MediaCache) EnsureMedia(ctx context.Context, reader io.Reader) (*CachedMedia, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	hash, header, body, err := hashMedia(reader)
+	if err != nil {
+		return nil, err
+	}
+	format := sniffMediaFormat(header)
+	mediaType := c.MediaType
+	if mediaType == "" {
+		if mediaType = mediaTypeOfFormat(format); mediaType == "" {
+			return nil, fmt.Errorf("%w：无法根据文件头推断素材类型", ErrUnsupportedMediaFormat)
+		}
+	}
+	key := mediaCacheKey(mediaType, c.Permanent, hash)
+
+	for {
+		media, err := c.store.Get(key)
+		if err != nil && !errors.Is(err, ErrMediaNotCached) {
+			return nil, err
+		}
+		if err == nil && c.valid(media) {
+			return media, nil
+		}
+
+		c.mutex.Lock()
+		if upload, ok := c.inflight[key]; ok {
+			c.mutex.Unlock()
+			// 等待相同内容的上传完成后重新读取缓存
+			select {
+			case <-upload.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if upload.err != nil {
+				return nil, upload.err
+			}
+			continue
+		}
+		upload := &mediaUpload{done: make(chan struct{})}
+		c.inflight[key] = upload
+		c.mutex.Unlock()
+
+		upload.media, upload.err = c.upload(ctx, mediaType, "media"+mediaFormatExtensions[format], hash, body)
+		if upload.err == nil {
+			upload.err = c.store.Put(key, *upload.media)
+		}
+		c.mutex.Lock()
+		delete(c.inflight, key)
+		c.mutex.Unlock()
+		close(upload.done)
+		return upload.media, upload.err
+	}
+}
+
+// Invalidate 删除缓存的素材，如素材已在公众平台被删除时调用
+func (c *MediaCache) Invalidate(media *CachedMedia) error {
+	return c.store.Delete(mediaCacheKey(media.MediaType, media.Permanent, media.Hash))
+}
+
+// valid 判断缓存的素材是否仍可使用
+func (c *MediaCache) valid(media *CachedMedia) bool {
+	if media.Permanent {
+		return true
+	}
+	return time.Now().Before(media.ExpiresAt().Add(-c.ExpiryMargin))
+}
+
+// upload 上传素材，filename的扩展名由文件头识别出的格式决定，微信根据扩展名识别文件格式
+func (c *MediaCache) upload(ctx context.Context, mediaType, filename, hash string, body io.Reader) (*CachedMedia, error) {
+	media := &CachedMedia{Hash: hash, MediaType: mediaType, Permanent: c.Permanent}
+	opts := append([]UploadOption{WithContext(ctx)}, c.UploadOptions...)
+	if !c.Permanent {
+		tempMedia, err := c.sdk.UploadTempMedia(mediaType, filename, "", body, opts...)
+		if err != nil {
+			return nil, err
+		}
+		media.MediaID, media.CreatedAt = tempMedia.MediaID, tempMedia.CreatedAt
+		if tempMedia.CreatedAt.Unix() <= 0 {
+			media.CreatedAt = time.Now()
+		}
+		return media, nil
+	}
+
+	var (
+		resp *AddMediaResponse
+		err  error
+	)
+	if mediaType == MediaTypeVideo {
+		resp, err = c.sdk.UploadVideoMaterial(filename, "", body, c.VideoDescription, opts...)
+	} else {
+		resp, err = c.sdk.UploadMaterial(mediaType, filename, "", body, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	media.MediaID, media.URL, media.CreatedAt = resp.MediaId, resp.URL, time.Now()
+	return media, nil
+}
+
+// hashMedia 计算内容的SHA-256，返回文件头及用于上传的reader
+func hashMedia(reader io.Reader) (string, []byte, io.Reader, error) {
+	hasher := sha256.New()
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		header := make([]byte, 512)
+		n, err := io.ReadFull(seeker, header)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", nil, nil, err
+		}
+		hasher.Write(header[:n])
+		if _, err = io.Copy(hasher, seeker); err != nil {
+			return "", nil, nil, err
+		}
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return "", nil, nil, err
+		}
+		return hex.EncodeToString(hasher.Sum(nil)), header[:n], seeker, nil
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	hasher.Write(data)
+	header := data
+	if len(header) > 512 {
+		header = header[:512]
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), header, bytes.NewReader(data), nil
+}
+
+// mediaTypeOfFormat 根据文件格式推断素材类型
+func mediaTypeOfFormat(format string) string {
+	switch format {
+	case mediaFormatJPEG, mediaFormatPNG, mediaFormatGIF, mediaFormatBMP:
+		return MediaTypeImage
+	case mediaFormatAMR, mediaFormatMP3:
+		return MediaTypeVoice
+	case mediaFormatMP4:
+		return MediaTypeVideo
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mediaUploadServer 模拟上传临时素材接口，记录上传次数及文件名
+func mediaUploadServer(t *testing.T, uploads *int32, filenames chan<- string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, header, err := r.FormFile("media")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		n := atomic.AddInt32(uploads, 1)
+		select {
+		case filenames <- header.Filename:
+		default:
+		}
+		fmt.Fprintf(w, `{"type":%q,"media_id":"media-%d","created_at":%d}`,
+			r.URL.Query().Get("type"), n, time.Now().Unix())
+	}
+}
+
+func TestMediaCacheEnsureMedia(t *testing.T) {
+	var uploads int32
+	filenames := make(chan string, 1)
+	sdk := newTestSDK(t, mediaUploadServer(t, &uploads, filenames))
+	cache := NewMediaCache(sdk, nil)
+	// 跳过校验时文件名的扩展名仍由识别出的格式决定
+	cache.UploadOptions = []UploadOption{WithoutValidation()}
+	data := noisePNG(t, 8, 8)
+
+	var wg sync.WaitGroup
+	results := make([]*CachedMedia, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			media, err := cache.EnsureMedia(context.Background(), bytes.NewReader(data))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = media
+		}(i)
+	}
+	wg.Wait()
+	if uploads != 1 {
+		t.Fatalf("uploaded %d times, want 1", uploads)
+	}
+	if filename := <-filenames; filename != "media.png" {
+		t.Fatalf("filename = %q, want media.png", filename)
+	}
+	for _, media := range results {
+		if media == nil || media.MediaID != "media-1" || media.MediaType != MediaTypeImage {
+			t.Fatalf("EnsureMedia() = %+v", media)
+		}
+	}
+
+	// 临时素材即将过期时重新上传
+	media := *results[0]
+	media.CreatedAt = time.Now().Add(-tempMediaLifetime + 30*time.Minute)
+	cache.store.Put(mediaCacheKey(media.MediaType, false, media.Hash), media)
+	renewed, err := cache.EnsureMedia(context.Background(), bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.MediaID != "media-2" || uploads != 2 {
+		t.Fatalf("EnsureMedia() = %+v after expiry", renewed)
+	}
+
+	if err = cache.Invalidate(renewed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cache.store.Get(mediaCacheKey(renewed.MediaType, false, renewed.Hash)); err != ErrMediaNotCached {
+		t.Fatalf("Get() after Invalidate error = %v", err)
+	}
+}
+
+func TestMediaCacheUnknownFormat(t *testing.T) {
+	var uploads int32
+	sdk := newTestSDK(t, mediaUploadServer(t, &uploads, nil))
+	cache := NewMediaCache(sdk, nil)
+	if _, err := cache.EnsureMedia(context.Background(), bytes.NewReader([]byte("text"))); err == nil {
+		t.Fatal("EnsureMedia() error = nil")
+	}
+	if uploads != 0 {
+		t.Fatalf("uploaded %d times, want 0", uploads)
+	}
+}
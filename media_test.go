@@ -0,0 +1,152 @@
+package wechat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadMedia(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaID   string
+		want      string
+		wantCode  int
+		wantVideo bool
+	}{
+		{name: "图片", mediaID: "image", want: "image-data"},
+		{name: "视频", mediaID: "video", want: "video-data", wantVideo: true},
+		{name: "接口错误", mediaID: "invalid", wantCode: 40007},
+		{name: "HTTP错误", mediaID: "status", wantCode: http.StatusBadGateway},
+		{name: "视频地址返回JSON", mediaID: "video-json"},
+		{name: "视频地址HTTP错误", mediaID: "video-status", wantCode: http.StatusNotFound},
+	}
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path + "?" + r.URL.Query().Get("media_id") {
+		case "/cgi-bin/media/get?image":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Disposition", `attachment; filename="a.jpg"`)
+			w.Write([]byte("image-data"))
+		case "/cgi-bin/media/get?video":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"video_url":"http://example.com/video"}`))
+		case "/cgi-bin/media/get?video-json":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"video_url":"http://example.com/json"}`))
+		case "/cgi-bin/media/get?video-status":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"video_url":"http://example.com/missing"}`))
+		case "/cgi-bin/media/get?invalid":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"errcode":40007,"errmsg":"invalid media_id"}`))
+		case "/video?":
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Write([]byte("video-data"))
+		case "/json?":
+			// 视频地址不应再返回JSON，避免循环跟随
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"video_url":"http://example.com/json"}`))
+		case "/missing?":
+			http.NotFound(w, r)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			file, err := sdk.DownloadMedia(tt.mediaID, &buf)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatal("DownloadMedia() error = nil")
+				}
+				var apiErr *APIError
+				if tt.wantCode != 0 && (!errors.As(err, &apiErr) || apiErr.Errcode != tt.wantCode) {
+					t.Fatalf("DownloadMedia() error = %v, want errcode %d", err, tt.wantCode)
+				}
+				if buf.Len() != 0 {
+					t.Fatalf("DownloadMedia() wrote %q on error", buf.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want || file.Size != int64(len(tt.want)) || (file.VideoURL != "") != tt.wantVideo {
+				t.Fatalf("DownloadMedia() = %+v, %q", file, buf.String())
+			}
+		})
+	}
+}
+
+func TestUploadTempMediaFile(t *testing.T) {
+	data := noisePNG(t, 4, 4)
+	path := filepath.Join(t.TempDir(), "cover.png")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("media")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if r.URL.Path != "/cgi-bin/media/upload" || header.Filename != "cover.png" || !bytes.Equal(content, data) {
+			t.Errorf("unexpected upload %s %s %d bytes", r.URL, header.Filename, len(content))
+		}
+		// 缩略图只返回thumb_media_id
+		fmt.Fprintf(w, `{"type":%q,"thumb_media_id":"thumb","created_at":1700000000}`, r.URL.Query().Get("type"))
+	})
+
+	media, err := sdk.UploadTempMediaFile(MediaTypeImage, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.Type != MediaTypeImage || media.MediaID != "thumb" || media.CreatedAt.Unix() != 1700000000 {
+		t.Fatalf("UploadTempMediaFile() = %+v", media)
+	}
+	if media.ExpiresAt().Sub(media.CreatedAt) != tempMediaLifetime {
+		t.Fatalf("ExpiresAt() = %s", media.ExpiresAt())
+	}
+	if _, err = sdk.UploadTempMediaFile(MediaTypeImage, filepath.Join(t.TempDir(), "missing.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("UploadTempMediaFile() missing file error = %v", err)
+	}
+}
+
+func TestFilenameFromURL(t *testing.T) {
+	tests := []struct {
+		url         string
+		contentType string
+		want        string
+	}{
+		{"https://example.com/a/cover.png?x=1", "image/jpeg", "cover.png"},
+		{"https://example.com/a/cover", "image/jpeg", "cover.jpg"},
+		{"https://example.com/", "audio/mpeg", "media.mp3"},
+		{"https://example.com/voice", "audio/amr; charset=binary", "voice.amr"},
+		{"https://example.com/file", "", "file"},
+	}
+	for _, tt := range tests {
+		if got := filenameFromURL(tt.url, tt.contentType); got != tt.want {
+			t.Errorf("filenameFromURL(%q, %q) = %q, want %q", tt.url, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestUploadTempMediaError(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":40004,"errmsg":"invalid media type"}`))
+	})
+
+	_, err := sdk.UploadTempMedia(MediaTypeImage, "cover.png", "", bytes.NewReader(noisePNG(t, 4, 4)))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Errcode != 40004 {
+		t.Fatalf("UploadTempMedia() error = %v", err)
+	}
+}
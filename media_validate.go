@@ -0,0 +1,423 @@
+package wechat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMediaTooLarge          = errors.New("素材超出大小限制")
+	ErrUnsupportedMediaFormat = errors.New("素材格式不支持")
+	ErrVoiceTooLong           = errors.New("语音超出时长限制")
+)
+
+// 素材的文件格式，根据文件头识别
+const (
+	mediaFormatJPEG = "jpeg"
+	mediaFormatPNG  = "png"
+	mediaFormatGIF  = "gif"
+	mediaFormatBMP  = "bmp"
+	mediaFormatAMR  = "amr"
+	mediaFormatMP3  = "mp3"
+	mediaFormatMP4  = "mp4"
+)
+
+// mediaFormatExtensions 文件格式对应的扩展名
+var mediaFormatExtensions = map[string]string{
+	mediaFormatJPEG: ".jpg",
+	mediaFormatPNG:  ".png",
+	mediaFormatGIF:  ".gif",
+	mediaFormatBMP:  ".bmp",
+	mediaFormatAMR:  ".amr",
+	mediaFormatMP3:  ".mp3",
+	mediaFormatMP4:  ".mp4",
+}
+
+// mediaLimit 素材类型的大小、格式和时长限制
+type mediaLimit struct {
+	maxSize     int64
+	formats     []string
+	maxDuration time.Duration
+}
+
+// mediaTypeArticleImage 图文消息内的图片，仅用于上传前的校验
+const mediaTypeArticleImage = "articleimage"
+
+// mediaLimits 各类型素材的上传限制
+var mediaLimits = map[string]mediaLimit{
+	mediaTypeArticleImage: {maxSize: 1 << 20, formats: []string{mediaFormatJPEG, mediaFormatPNG}},
+	MediaTypeImage:        {maxSize: 10 << 20, formats: []string{mediaFormatJPEG, mediaFormatPNG, mediaFormatGIF, mediaFormatBMP}},
+	MediaTypeThumb:        {maxSize: 2 << 20, formats: []string{mediaFormatJPEG}},
+	MediaTypeVoice:        {maxSize: 2 << 20, formats: []string{mediaFormatAMR, mediaFormatMP3}, maxDuration: 60 * time.Second},
+	MediaTypeVideo:        {maxSize: 10 << 20, formats: []string{mediaFormatMP4}},
+}
+
+// UploadOption 上传素材的选项
+type UploadOption func(*uploadOptions)
+
+type uploadOptions struct {
+	ctx            context.Context
+	progress       func(uploaded, total int64)
+	autoResize     bool
+	skipValidation bool
+}
+
+// WithContext 设置上传请求的ctx，ctx被取消时中止上传
+func WithContext(ctx context.Context) UploadOption {
+	return func(o *uploadOptions) {
+		if ctx != nil {
+			o.ctx = ctx
+		}
+	}
+}
+
+// WithProgress 设置上传进度回调，uploaded为已上传的文件字节数，total为文件大小，未知时为-1
+func WithProgress(progress func(uploaded, total int64)) UploadOption {
+	return func(o *uploadOptions) {
+		o.progress = progress
+	}
+}
+
+// WithAutoResize 图片或缩略图超出大小限制时自动缩小并重新编码，仅支持JPEG和PNG格式
+// 缩略图只支持JPG格式，PNG缩略图会被转换为JPEG
+func WithAutoResize() UploadOption {
+	return func(o *uploadOptions) {
+		o.autoResize = true
+	}
+}
+
+// WithoutValidation 跳过上传前的格式、大小及时长校验
+func WithoutValidation() UploadOption {
+	return func(o *uploadOptions) {
+		o.skipValidation = true
+	}
+}
+
+func newUploadOptions(opts []UploadOption) *uploadOptions {
+	o := &uploadOptions{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// prepareMedia 按素材类型校验文件格式、大小和时长，返回用于上传的文件
+// 文件名没有扩展名时按识别出的格式补充；超出大小的内容会在读取过程中报错
+func prepareMedia(mediaType string, file multipartFile, o *uploadOptions) (multipartFile, error) {
+	limit, ok := mediaLimits[mediaType]
+	if o.skipValidation || !ok {
+		return file, nil
+	}
+
+	reader := bufio.NewReaderSize(file.reader, 512)
+	header, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return file, err
+	}
+	format := sniffMediaFormat(header)
+	file.reader = reader
+
+	resizable := o.autoResize && (format == mediaFormatJPEG || format == mediaFormatPNG) &&
+		(mediaType == MediaTypeImage || mediaType == MediaTypeThumb || mediaType == mediaTypeArticleImage)
+	if !resizable && !containsString(limit.formats, format) {
+		if format == "" {
+			format = "未知"
+		}
+		return file, fmt.Errorf("%w：%s类型的素材只支持%s格式，当前为%s格式",
+			ErrUnsupportedMediaFormat, mediaType, strings.Join(limit.formats, "、"), format)
+	}
+
+	switch {
+	case resizable:
+		data, err := io.ReadAll(io.LimitReader(reader, maxResizeInputSize+1))
+		if err != nil {
+			return file, err
+		}
+		if int64(len(data)) > maxResizeInputSize {
+			return file, fmt.Errorf("%w：自动缩小的图片最大为%dMB", ErrMediaTooLarge, maxResizeInputSize>>20)
+		}
+		target := format
+		if !containsString(limit.formats, format) {
+			target = limit.formats[0]
+		}
+		data, err = shrinkImage(data, target, limit.maxSize)
+		if err != nil {
+			return file, err
+		}
+		if target != format {
+			// 仅替换最后一个扩展名，如 a.b.png 转换后为 a.b.jpg
+			file.filename = strings.TrimSuffix(file.filename, filepath.Ext(file.filename)) + mediaFormatExtensions[target]
+			file.contentType = "image/" + target
+		}
+		format = target
+		file.reader, file.size = bytes.NewReader(data), int64(len(data))
+	case limit.maxDuration > 0:
+		// 语音文件较小，读取全部内容以计算时长
+		data, err := io.ReadAll(io.LimitReader(reader, limit.maxSize+1))
+		if err != nil {
+			return file, err
+		}
+		if int64(len(data)) > limit.maxSize {
+			return file, fmt.Errorf("%w：%s类型的素材最大为%dMB", ErrMediaTooLarge, mediaType, limit.maxSize>>20)
+		}
+		if duration := audioDuration(format, data); duration > limit.maxDuration {
+			return file, fmt.Errorf("%w：语音最长为%s，当前为%s", ErrVoiceTooLong, limit.maxDuration, duration.Round(time.Millisecond))
+		}
+		file.reader, file.size = bytes.NewReader(data), int64(len(data))
+	default:
+		if file.size > limit.maxSize {
+			return file, fmt.Errorf("%w：%s类型的素材最大为%dMB", ErrMediaTooLarge, mediaType, limit.maxSize>>20)
+		}
+		file.reader = &sizeLimitReader{reader: reader, remaining: limit.maxSize, mediaType: mediaType}
+	}
+
+	if filepath.Ext(file.filename) == "" {
+		file.filename += mediaFormatExtensions[format]
+	}
+	return file, nil
+}
+
+// sizeLimitReader 读取超出大小限制时返回 ErrMediaTooLarge
+type sizeLimitReader struct {
+	reader    io.Reader
+	remaining int64
+	mediaType string
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, fmt.Errorf("%w：%s类型的素材最大为%dMB", ErrMediaTooLarge, r.mediaType, mediaLimits[r.mediaType].maxSize>>20)
+	}
+	return n, err
+}
+
+// sniffMediaFormat 根据文件头识别素材格式，无法识别时返回空
+func sniffMediaFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return mediaFormatJPEG
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return mediaFormatPNG
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return mediaFormatGIF
+	case bytes.HasPrefix(header, []byte("BM")):
+		return mediaFormatBMP
+	case bytes.HasPrefix(header, []byte("#!AMR")):
+		return mediaFormatAMR
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return mediaFormatMP3
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE6 == 0xE2:
+		// MPEG音频帧同步字，且为Layer III
+		return mediaFormatMP3
+	case len(header) >= 12 && string(header[4:8]) == "ftyp" && containsString(mp4Brands, string(header[8:12])):
+		return mediaFormatMP4
+	}
+	return ""
+}
+
+// mp4Brands MP4文件ftyp中的主品牌，mov、3gp等其他ISO媒体格式不在此列
+var mp4Brands = []string{"isom", "iso2", "iso3", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "M4V ", "dash"}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// audioDuration 计算AMR或MP3音频的时长，无法解析时返回0
+func audioDuration(format string, data []byte) time.Duration {
+	switch format {
+	case mediaFormatAMR:
+		return amrDuration(data)
+	case mediaFormatMP3:
+		return mp3Duration(data)
+	}
+	return 0
+}
+
+// AMR每帧数据的字节数（不含帧头），按帧头中的FT索引
+var (
+	amrNBFrameSizes = [16]int{12, 13, 15, 17, 19, 20, 26, 31, 5, 6, 5, 5, 0, 0, 0, 0}
+	amrWBFrameSizes = [16]int{17, 23, 32, 36, 40, 46, 50, 58, 60, 5, 0, 0, 0, 0, 0, 0}
+)
+
+// amrDuration 按帧数计算AMR音频时长，每帧20毫秒
+func amrDuration(data []byte) time.Duration {
+	sizes := amrNBFrameSizes
+	switch {
+	case bytes.HasPrefix(data, []byte("#!AMR-WB\n")):
+		sizes = amrWBFrameSizes
+		data = data[len("#!AMR-WB\n"):]
+	case bytes.HasPrefix(data, []byte("#!AMR\n")):
+		data = data[len("#!AMR\n"):]
+	default:
+		return 0
+	}
+	frames := 0
+	for pos := 0; pos < len(data); frames++ {
+		pos += 1 + sizes[(data[pos]>>3)&0x0F]
+	}
+	return time.Duration(frames) * 20 * time.Millisecond
+}
+
+// MPEG Layer III 的比特率（kbps）和采样率表
+var (
+	mp3BitratesV1 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3Samplerate = [3]int{44100, 48000, 32000}
+)
+
+// mp3Duration 逐帧累加MP3音频时长，支持固定码率和可变码率
+func mp3Duration(data []byte) time.Duration {
+	pos := 0
+	// 跳过ID3v2标签
+	if len(data) >= 10 && bytes.HasPrefix(data, []byte("ID3")) {
+		size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+		pos = 10 + size
+		if data[5]&0x10 != 0 {
+			pos += 10
+		}
+	}
+
+	var seconds float64
+	for pos+4 <= len(data) {
+		h := uint32(data[pos])<<24 | uint32(data[pos+1])<<16 | uint32(data[pos+2])<<8 | uint32(data[pos+3])
+		version := (h >> 19) & 3 // 3为MPEG1，2为MPEG2，0为MPEG2.5
+		layer := (h >> 17) & 3   // 1为Layer III
+		bitrateIndex := (h >> 12) & 0x0F
+		samplerateIndex := (h >> 10) & 3
+		if h>>21 != 0x7FF || version == 1 || layer != 1 || samplerateIndex == 3 ||
+			bitrateIndex == 0 || bitrateIndex == 15 {
+			pos++
+			continue
+		}
+
+		samplerate := mp3Samplerate[samplerateIndex]
+		bitrate, samples, coefficient := mp3BitratesV1[bitrateIndex], 1152, 144
+		switch version {
+		case 2:
+			samplerate /= 2
+			bitrate, samples, coefficient = mp3BitratesV2[bitrateIndex], 576, 72
+		case 0:
+			samplerate /= 4
+			bitrate, samples, coefficient = mp3BitratesV2[bitrateIndex], 576, 72
+		}
+		frameLength := coefficient*bitrate*1000/samplerate + int((h>>9)&1)
+		seconds += float64(samples) / float64(samplerate)
+		pos += frameLength
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+const (
+	maxResizeAttempts  = 8        // 自动缩小图片的最大尝试次数
+	maxResizeInputSize = 64 << 20 // 自动缩小的图片文件最大为64MB
+	maxResizePixels    = 50000000 // 自动缩小的图片最多5000万像素，避免解码高压缩比的图片时占用过多内存
+)
+
+// shrinkImage 将图片编码为format格式，超出maxSize时按比例缩小，直到满足大小限制
+func shrinkImage(data []byte, format string, maxSize int64) ([]byte, error) {
+	if int64(len(data)) <= maxSize && sniffMediaFormat(data) == format {
+		return data, nil
+	}
+	// 解码前先读取尺寸，拒绝像素过多的图片
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxResizePixels {
+		return nil, fmt.Errorf("%w：图片尺寸%dx%d超出自动缩小的像素上限", ErrMediaTooLarge, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	scale := 1.0
+	out := data
+	for i := 0; i < maxResizeAttempts; i++ {
+		// 文件大小与像素数近似成正比，按比例缩小；需要转换格式时先按原尺寸编码
+		if i > 0 || sniffMediaFormat(data) == format {
+			scale *= math.Min(0.9, math.Sqrt(float64(maxSize)/float64(len(out))))
+		}
+		width := int(math.Max(1, float64(bounds.Dx())*scale))
+		height := int(math.Max(1, float64(bounds.Dy())*scale))
+
+		var buf bytes.Buffer
+		resized := resizeImage(img, width, height)
+		if format == mediaFormatPNG {
+			err = png.Encode(&buf, resized)
+		} else {
+			// JPEG不支持透明度，透明区域以白色填充
+			flattened := image.NewRGBA(resized.Bounds())
+			draw.Draw(flattened, flattened.Bounds(), image.White, image.Point{}, draw.Src)
+			draw.Draw(flattened, flattened.Bounds(), resized, image.Point{}, draw.Over)
+			err = jpeg.Encode(&buf, flattened, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = buf.Bytes()
+		if int64(len(out)) <= maxSize {
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("%w：图片缩小后仍超出%dMB", ErrMediaTooLarge, maxSize>>20)
+}
+
+// resizeImage 按区域平均缩放图片
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == bounds.Dx() && height == bounds.Dy() {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				dst.Set(x, y, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,178 @@
+package wechat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSniffMediaFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"jpeg", "\xFF\xD8\xFF\xE0", mediaFormatJPEG},
+		{"png", "\x89PNG\r\n\x1a\n", mediaFormatPNG},
+		{"gif", "GIF89a", mediaFormatGIF},
+		{"bmp", "BM", mediaFormatBMP},
+		{"amr", "#!AMR\n", mediaFormatAMR},
+		{"mp3 id3", "ID3\x04", mediaFormatMP3},
+		{"mp3 frame", "\xFF\xFB\x90\x00", mediaFormatMP3},
+		{"mp4 isom", "\x00\x00\x00\x20ftypisom", mediaFormatMP4},
+		{"mp4 mp42", "\x00\x00\x00\x18ftypmp42", mediaFormatMP4},
+		{"mov", "\x00\x00\x00\x14ftypqt  ", ""},
+		{"heic", "\x00\x00\x00\x18ftypheic", ""},
+		{"ftyp without brand", "\x00\x00\x00\x08ftyp", ""},
+		{"unknown", "hello", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffMediaFormat([]byte(tt.header)); got != tt.want {
+				t.Fatalf("sniffMediaFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// amrFrames 生成count帧AMR-NB音频，每帧20毫秒
+func amrFrames(count int) []byte {
+	data := []byte("#!AMR\n")
+	for i := 0; i < count; i++ {
+		// FT为7（12.2kbps），每帧31字节数据
+		data = append(data, 0x3C)
+		data = append(data, make([]byte, 31)...)
+	}
+	return data
+}
+
+// mp3Frames 生成count帧128kbps、44.1kHz的MPEG1 Layer III音频，每帧约26毫秒
+func mp3Frames(count int, id3 bool) []byte {
+	var data []byte
+	if id3 {
+		data = append(data, "ID3\x04\x00\x00\x00\x00\x00\x0A"...)
+		data = append(data, make([]byte, 10)...)
+	}
+	for i := 0; i < count; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		data = append(data, frame...)
+	}
+	return data
+}
+
+func TestAudioDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+		want   time.Duration
+	}{
+		{"amr", mediaFormatAMR, amrFrames(150), 3 * time.Second},
+		{"amr-wb", mediaFormatAMR, append([]byte("#!AMR-WB\n"), 0x44, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0), 20 * time.Millisecond},
+		{"mp3", mediaFormatMP3, mp3Frames(100, false), 2612244897},
+		{"mp3 with id3", mediaFormatMP3, mp3Frames(100, true), 2612244897},
+		{"unknown", mediaFormatMP4, amrFrames(10), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := audioDuration(tt.format, tt.data)
+			if diff := got - tt.want; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Fatalf("audioDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// noisePNG 生成难以压缩的PNG图片
+func noisePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	random := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hugePNGHeader 返回尺寸被改为width x height的小PNG，模拟高压缩比的超大图片，解码像素数据前即应被拒绝
+func hugePNGHeader(t *testing.T, width, height uint32) []byte {
+	data := noisePNG(t, 4, 4)
+	// 签名8字节，IHDR块的长度和类型8字节，之后为宽、高，块的CRC位于偏移29
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestPrepareMedia(t *testing.T) {
+	smallPNG := noisePNG(t, 16, 16)
+	largePNG := noisePNG(t, 700, 700)
+	tests := []struct {
+		name            string
+		mediaType       string
+		filename        string
+		data            []byte
+		opts            []UploadOption
+		wantErr         error
+		wantFilename    string
+		wantFormat      string
+		wantContentType string
+	}{
+		{name: "补充扩展名", mediaType: MediaTypeImage, filename: "cover", data: smallPNG, wantFilename: "cover.png", wantFormat: mediaFormatPNG},
+		{name: "格式不支持", mediaType: MediaTypeThumb, filename: "a.png", data: smallPNG, wantErr: ErrUnsupportedMediaFormat},
+		{name: "缩略图转为JPEG", mediaType: MediaTypeThumb, filename: "a.b.png", data: smallPNG, opts: []UploadOption{WithAutoResize()},
+			wantFilename: "a.b.jpg", wantFormat: mediaFormatJPEG, wantContentType: "image/jpeg"},
+		{name: "超出大小", mediaType: mediaTypeArticleImage, filename: "a.png", data: largePNG, wantErr: ErrMediaTooLarge},
+		{name: "自动缩小", mediaType: mediaTypeArticleImage, filename: "a.png", data: largePNG, opts: []UploadOption{WithAutoResize()},
+			wantFilename: "a.png", wantFormat: mediaFormatPNG},
+		{name: "像素过多", mediaType: MediaTypeThumb, filename: "a.png", data: hugePNGHeader(t, 20000, 20000), opts: []UploadOption{WithAutoResize()},
+			wantErr: ErrMediaTooLarge},
+		{name: "跳过校验", mediaType: MediaTypeThumb, filename: "a.png", data: smallPNG, opts: []UploadOption{WithoutValidation()},
+			wantFilename: "a.png", wantFormat: mediaFormatPNG},
+		{name: "语音", mediaType: MediaTypeVoice, filename: "a", data: amrFrames(2999), wantFilename: "a.amr", wantFormat: mediaFormatAMR},
+		{name: "语音过长", mediaType: MediaTypeVoice, filename: "a.amr", data: amrFrames(3001), wantErr: ErrVoiceTooLong},
+		{name: "视频格式", mediaType: MediaTypeVideo, filename: "a.mov", data: []byte("\x00\x00\x00\x14ftypqt  "), wantErr: ErrUnsupportedMediaFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := multipartFile{field: "media", filename: tt.filename, reader: bytes.NewReader(tt.data), size: int64(len(tt.data))}
+			file, err := prepareMedia(tt.mediaType, file, newUploadOptions(tt.opts))
+			if err == nil {
+				// 大小超出限制时在读取过程中报错
+				var data []byte
+				if data, err = io.ReadAll(file.reader); err == nil && tt.wantErr == nil {
+					if format := sniffMediaFormat(data); format != tt.wantFormat {
+						t.Fatalf("uploaded format = %q, want %q", format, tt.wantFormat)
+					}
+					if limit, ok := mediaLimits[tt.mediaType]; ok && int64(len(data)) > limit.maxSize {
+						t.Fatalf("uploaded %d bytes, limit %d", len(data), limit.maxSize)
+					}
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("prepareMedia() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if file.filename != tt.wantFilename || file.contentType != tt.wantContentType {
+				t.Fatalf("prepareMedia() filename = %q, contentType = %q", file.filename, file.contentType)
+			}
+		})
+	}
+}
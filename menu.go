@@ -0,0 +1,121 @@
+package wechat
+
+import "fmt"
+
+// AddConditionalMenu 创建个性化菜单，返回菜单ID，需先创建默认菜单，提交前会先调用 Validate 校验菜单
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Personalized_menu_interface.html
+func (s *SDK) AddConditionalMenu(menu ConditionalMenu) (MenuID, error) {
+	if err := menu.Validate(); err != nil {
+		return "", err
+	}
+	if err := s.checkAccessToken(); err != nil {
+		return "", err
+	}
+	// 创建时不需要提交菜单ID
+	menu.MenuID = ""
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/menu/addconditional?access_token=%s", s.AccessToken)
+
+	var responseJson AddConditionalMenuResponse
+	if err := postJSON(url, menu, &responseJson); err != nil {
+		return "", err
+	}
+	if responseJson.Errcode != 0 {
+		return "", ErrorHandler(ErrAddConditionalMenu, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.MenuID, nil
+}
+
+// DelConditionalMenu 删除个性化菜单
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Personalized_menu_interface.html
+func (s *SDK) DelConditionalMenu(menuID MenuID) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/menu/delconditional?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, map[string]interface{}{"menuid": menuID}, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDelConditionalMenu, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// TryMatchMenu 测试个性化菜单匹配结果，userID可以是粉丝的openid，也可以是粉丝的微信号
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Personalized_menu_interface.html
+func (s *SDK) TryMatchMenu(userID string) (*Menu, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/menu/trymatch?access_token=%s", s.AccessToken)
+
+	var responseJson TryMatchMenuResponse
+	if err := postJSON(url, map[string]interface{}{"user_id": userID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrTryMatchMenu, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson.Menu, nil
+}
+
+// GetMenu 查询使用API设置的自定义菜单，包括默认菜单和全部个性化菜单
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Getting_Custom_Menu_Configurations.html
+func (s *SDK) GetMenu() (*GetMenuResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/menu/get?access_token=%s", s.AccessToken)
+
+	var responseJson GetMenuResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	// 46003 菜单不存在，返回空菜单
+	if responseJson.Errcode == 46003 {
+		return &GetMenuResponse{}, nil
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetMenu, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// DeleteMenu 删除自定义菜单，会同时删除全部个性化菜单
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Deleting_Custom-Defined_Menu.html
+func (s *SDK) DeleteMenu() error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/menu/delete?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := getJSON(url, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDeleteMenu, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// GetCurrentSelfMenuInfo 获取当前使用的自定义菜单配置，无论菜单是通过API还是公众平台官网设置的
+// 官网设置的菜单中可能包含 text、img、voice、video、news 等类型的按钮，其内容分别在 Value、NewsInfo 中
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Querying_Custom_Menus.html
+func (s *SDK) GetCurrentSelfMenuInfo() (*GetCurrentSelfMenuInfoResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/get_current_selfmenu_info?access_token=%s", s.AccessToken)
+
+	var responseJson GetCurrentSelfMenuInfoResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetCurrentSelfMenuInfo, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
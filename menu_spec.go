@@ -0,0 +1,384 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MenuSpec 声明式的菜单配置，字段与查询菜单接口返回的结构一致，可保存为YAML或JSON文件
+//
+//	menu:
+//	  button:
+//	    - type: click
+//	      name: 联系客服
+//	      key: CONTACT_US
+//	conditionalmenu:
+//	  - matchrule:
+//	      tag_id: "100"
+//	    button:
+//	      - type: view
+//	        name: 会员中心
+//	        url: https://www.example.com/vip
+type MenuSpec struct {
+	Menu            Menu              `json:"menu"`            // 默认菜单
+	ConditionalMenu []ConditionalMenu `json:"conditionalmenu"` // 个性化菜单，按匹配规则与线上菜单对应
+}
+
+// LoadMenuSpec 读取并校验菜单配置文件，扩展名为 .yaml 或 .yml 时按YAML解析，否则按JSON解析
+func LoadMenuSpec(path string) (*MenuSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseMenuSpecYAML(data)
+	default:
+		return ParseMenuSpecJSON(data)
+	}
+}
+
+// ParseMenuSpecJSON 解析并校验JSON格式的菜单配置
+func ParseMenuSpecJSON(data []byte) (*MenuSpec, error) {
+	var spec MenuSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("解析菜单配置失败：%w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// ParseMenuSpecYAML 解析并校验YAML格式的菜单配置
+// 菜单配置的字段均为字符串，YAML中未加引号的数字、布尔值按字符串处理，如 tag_id: 100
+func ParseMenuSpecYAML(data []byte) (*MenuSpec, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析菜单配置失败：%w", err)
+	}
+	// 转为JSON后解析，复用JSON标签及 MenuButton 的解析逻辑
+	jsonData, err := json.Marshal(yamlToJSONValue(raw))
+	if err != nil {
+		return nil, fmt.Errorf("解析菜单配置失败：%w", err)
+	}
+	return ParseMenuSpecJSON(jsonData)
+}
+
+// yamlToJSONValue 将YAML解析结果转换为可序列化为JSON的值，标量统一转为字符串
+func yamlToJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = yamlToJSONValue(item)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = yamlToJSONValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = yamlToJSONValue(item)
+		}
+		return result
+	case nil, string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Validate 校验默认菜单和全部个性化菜单，错误为 *MenuValidationError，路径以 menu. 或 conditionalmenu[i]. 开头
+func (spec *MenuSpec) Validate() error {
+	result := &MenuValidationError{}
+	validateMenuButtons(result, "menu.", spec.Menu.Button)
+	for i, menu := range spec.ConditionalMenu {
+		prefix := fmt.Sprintf("conditionalmenu[%d].", i)
+		validateConditionalMenu(result, prefix, menu)
+		for j := 0; j < i; j++ {
+			if spec.ConditionalMenu[j].MatchRule.normalized() == menu.MatchRule.normalized() {
+				result.add(prefix+"matchrule", "与conditionalmenu[%d]的匹配规则重复", j)
+				break
+			}
+		}
+	}
+	return result.err()
+}
+
+// MenuChangeAction 菜单变更类型
+type MenuChangeAction string
+
+const (
+	MenuChangeCreate MenuChangeAction = "create" // 新建
+	MenuChangeUpdate MenuChangeAction = "update" // 更新，个性化菜单通过删除后重新创建实现
+	MenuChangeDelete MenuChangeAction = "delete" // 删除，仅用于个性化菜单
+)
+
+// MenuChange 一项菜单变更
+type MenuChange struct {
+	Action      MenuChangeAction
+	Conditional bool         // 是否为个性化菜单
+	MatchRule   MatchRule    // 个性化菜单的匹配规则
+	MenuID      MenuID       // 线上个性化菜单的ID，更新、删除时使用
+	Button      []MenuButton // 变更后的菜单按钮，删除时为空
+	Diff        []string     // 按钮的差异，+ 为新增，- 为删除，~ 为修改
+}
+
+// title 变更的描述，如 个性化菜单(tag_id=100) 新建
+func (c MenuChange) title() string {
+	target := "默认菜单"
+	if c.Conditional {
+		target = fmt.Sprintf("个性化菜单(%s)", describeMatchRule(c.MatchRule))
+	}
+	switch c.Action {
+	case MenuChangeCreate:
+		return target + " 新建"
+	case MenuChangeUpdate:
+		return target + " 更新"
+	default:
+		return fmt.Sprintf("%s 删除，menuid=%s", target, c.MenuID)
+	}
+}
+
+// MenuPlan 菜单配置与线上菜单的差异，由 PlanMenu 生成，通过 ApplyMenuPlan 执行
+type MenuPlan struct {
+	Changes []MenuChange // 按执行顺序排列：默认菜单、删除的个性化菜单、其余个性化菜单
+}
+
+// HasChanges 是否存在需要执行的变更
+func (p *MenuPlan) HasChanges() bool {
+	return p != nil && len(p.Changes) > 0
+}
+
+// String 返回可读的变更计划
+func (p *MenuPlan) String() string {
+	if !p.HasChanges() {
+		return "菜单没有变化"
+	}
+	var builder strings.Builder
+	for _, change := range p.Changes {
+		builder.WriteString(change.title())
+		builder.WriteString("：\n")
+		for _, line := range change.Diff {
+			builder.WriteString("  ")
+			builder.WriteString(line)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+// PlanMenu 校验菜单配置，查询线上菜单并生成变更计划
+// 个性化菜单按匹配规则与线上菜单对应，配置中不存在的线上个性化菜单将被删除
+func (s *SDK) PlanMenu(spec *MenuSpec) (*MenuPlan, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	live, err := s.GetMenu()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MenuPlan{}
+	if diff := diffMenuButtons(live.Menu.Button, spec.Menu.Button); len(diff) > 0 {
+		action := MenuChangeUpdate
+		if len(live.Menu.Button) == 0 {
+			action = MenuChangeCreate
+		}
+		plan.Changes = append(plan.Changes, MenuChange{Action: action, Button: spec.Menu.Button, Diff: diff})
+	}
+
+	var changes []MenuChange
+	used := make([]bool, len(live.ConditionalMenu))
+	for _, menu := range spec.ConditionalMenu {
+		matched := -1
+		rule := menu.MatchRule.normalized()
+		for i, liveMenu := range live.ConditionalMenu {
+			if !used[i] && liveMenu.MatchRule.normalized() == rule {
+				matched = i
+				break
+			}
+		}
+		if matched < 0 {
+			changes = append(changes, MenuChange{
+				Action:      MenuChangeCreate,
+				Conditional: true,
+				MatchRule:   menu.MatchRule,
+				Button:      menu.Button,
+				Diff:        diffMenuButtons(nil, menu.Button),
+			})
+			continue
+		}
+		used[matched] = true
+		liveMenu := live.ConditionalMenu[matched]
+		if diff := diffMenuButtons(liveMenu.Button, menu.Button); len(diff) > 0 {
+			changes = append(changes, MenuChange{
+				Action:      MenuChangeUpdate,
+				Conditional: true,
+				MatchRule:   menu.MatchRule,
+				MenuID:      liveMenu.MenuID,
+				Button:      menu.Button,
+				Diff:        diff,
+			})
+		}
+	}
+	// 先删除多余的个性化菜单，避免超出数量限制
+	for i, liveMenu := range live.ConditionalMenu {
+		if used[i] {
+			continue
+		}
+		plan.Changes = append(plan.Changes, MenuChange{
+			Action:      MenuChangeDelete,
+			Conditional: true,
+			MatchRule:   liveMenu.MatchRule,
+			MenuID:      liveMenu.MenuID,
+			Diff:        diffMenuButtons(liveMenu.Button, nil),
+		})
+	}
+	plan.Changes = append(plan.Changes, changes...)
+	return plan, nil
+}
+
+// ApplyMenuPlan 按顺序执行变更计划，没有变更时不调用任何接口；执行出错时立即返回，已执行的变更不会回滚
+func (s *SDK) ApplyMenuPlan(plan *MenuPlan) error {
+	if !plan.HasChanges() {
+		return nil
+	}
+	for _, change := range plan.Changes {
+		if err := s.applyMenuChange(change); err != nil {
+			return fmt.Errorf("%s失败：%w", change.title(), err)
+		}
+	}
+	return nil
+}
+
+func (s *SDK) applyMenuChange(change MenuChange) error {
+	if !change.Conditional {
+		return s.CreateMenu(Menu{Button: change.Button})
+	}
+	if change.Action != MenuChangeCreate {
+		if err := s.DelConditionalMenu(change.MenuID); err != nil {
+			return err
+		}
+	}
+	if change.Action == MenuChangeDelete {
+		return nil
+	}
+	_, err := s.AddConditionalMenu(ConditionalMenu{Button: change.Button, MatchRule: change.MatchRule})
+	return err
+}
+
+// diffMenuButtons 按按钮路径比较两组菜单按钮，相同时返回空
+func diffMenuButtons(oldButtons, newButtons []MenuButton) []string {
+	oldPaths, oldLines := flattenMenuButtons(oldButtons)
+	newPaths, newLines := flattenMenuButtons(newButtons)
+
+	var diff []string
+	for _, path := range newPaths {
+		oldLine, ok := oldLines[path]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("+ %s: %s", path, newLines[path]))
+		case oldLine != newLines[path]:
+			diff = append(diff, fmt.Sprintf("~ %s: %s => %s", path, oldLine, newLines[path]))
+		}
+	}
+	for _, path := range oldPaths {
+		if _, ok := newLines[path]; !ok {
+			diff = append(diff, fmt.Sprintf("- %s: %s", path, oldLines[path]))
+		}
+	}
+	return diff
+}
+
+// flattenMenuButtons 将菜单按钮展开为 路径 => 描述，并返回路径的顺序
+func flattenMenuButtons(buttons []MenuButton) ([]string, map[string]string) {
+	var paths []string
+	lines := make(map[string]string)
+	for i, button := range buttons {
+		path := fmt.Sprintf("button[%d]", i)
+		paths = append(paths, path)
+		lines[path] = describeMenuButton(button)
+		for j, sub := range button.SubButton {
+			subPath := fmt.Sprintf("%s.sub_button[%d]", path, j)
+			paths = append(paths, subPath)
+			lines[subPath] = describeMenuButton(sub)
+		}
+	}
+	return paths, lines
+}
+
+// describeMenuButton 返回按钮的可读描述，如 "官网" view url=https://www.example.com
+func describeMenuButton(button MenuButton) string {
+	parts := []string{fmt.Sprintf("%q", button.Name)}
+	fields := []struct{ name, value string }{
+		{"", button.Type},
+		{"key", button.Key},
+		{"url", button.Url},
+		{"appid", button.AppID},
+		{"pagepath", button.PagePath},
+		{"media_id", button.MediaID},
+		{"article_id", button.ArticleID},
+	}
+	for _, field := range fields {
+		switch {
+		case field.value == "":
+		case field.name == "":
+			parts = append(parts, field.value)
+		default:
+			parts = append(parts, field.name+"="+field.value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// normalized 返回用于比较的匹配规则，去除首尾空白，性别和客户端版本为0时视为未填写
+// 查询菜单接口返回的匹配规则可能包含空白或0值，直接比较会导致相同的规则无法对应
+func (r MatchRule) normalized() MatchRule {
+	rule := MatchRule{
+		TagID:              strings.TrimSpace(r.TagID),
+		Sex:                strings.TrimSpace(r.Sex),
+		Country:            strings.TrimSpace(r.Country),
+		Province:           strings.TrimSpace(r.Province),
+		City:               strings.TrimSpace(r.City),
+		ClientPlatformType: strings.TrimSpace(r.ClientPlatformType),
+		Language:           strings.TrimSpace(r.Language),
+	}
+	if rule.Sex == "0" {
+		rule.Sex = ""
+	}
+	if rule.ClientPlatformType == "0" {
+		rule.ClientPlatformType = ""
+	}
+	return rule
+}
+
+// describeMatchRule 返回匹配规则中已填写的字段，如 tag_id=100, client_platform_type=1
+func describeMatchRule(rule MatchRule) string {
+	fields := []struct{ name, value string }{
+		{"tag_id", rule.TagID},
+		{"sex", rule.Sex},
+		{"country", rule.Country},
+		{"province", rule.Province},
+		{"city", rule.City},
+		{"client_platform_type", rule.ClientPlatformType},
+		{"language", rule.Language},
+	}
+	var parts []string
+	for _, field := range fields {
+		if field.value != "" {
+			parts = append(parts, field.name+"="+field.value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
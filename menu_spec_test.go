@@ -0,0 +1,128 @@
+package wechat
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// liveMenuPayload 查询菜单接口的真实返回，数字字段、旧版group_id及空的sub_button均与线上一致
+const liveMenuPayload = `{
+	"menu": {
+		"button": [
+			{"type": "click", "name": "今日歌曲", "key": "V1001_TODAY_MUSIC", "sub_button": []},
+			{"name": "菜单", "sub_button": [
+				{"type": "view", "name": "搜索", "url": "http://www.soso.com/", "sub_button": []},
+				{"type": "click", "name": "赞一下我们", "key": "V1001_GOOD", "sub_button": []}
+			]}
+		],
+		"menuid": 208396938
+	},
+	"conditionalmenu": [
+		{
+			"button": [
+				{"type": "click", "name": "今日歌曲", "key": "V1001_TODAY_MUSIC", "sub_button": []}
+			],
+			"matchrule": {"group_id": 2, "sex": 0, "country": "", "province": "", "city": "", "client_platform_type": 2, "language": ""},
+			"menuid": 208396993
+		}
+	]
+}`
+
+const menuSpecYAML = `
+menu:
+  button:
+    - type: click
+      name: 今日歌曲
+      key: V1001_TODAY_MUSIC
+    - name: 菜单
+      sub_button:
+        - type: view
+          name: 搜索
+          url: http://www.soso.com/
+        - type: click
+          name: 赞一下我们
+          key: V1001_GOOD
+conditionalmenu:
+  - matchrule:
+      tag_id: 2
+      client_platform_type: 2
+    button:
+      - type: click
+        name: 今日歌曲
+        key: V1001_TODAY_MUSIC
+`
+
+func menuServer(t *testing.T, payload string, calls *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, r.URL.Path)
+		switch r.URL.Path {
+		case "/cgi-bin/menu/get":
+			w.Write([]byte(payload))
+		default:
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","menuid":"1"}`))
+		}
+	}
+}
+
+func TestPlanMenuWithoutChanges(t *testing.T) {
+	var calls []string
+	sdk := newTestSDK(t, menuServer(t, liveMenuPayload, &calls))
+	spec, err := ParseMenuSpecYAML([]byte(menuSpecYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := sdk.PlanMenu(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.HasChanges() {
+		t.Fatalf("PlanMenu() = %s", plan)
+	}
+	if err = sdk.ApplyMenuPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("API calls = %v, want only menu/get", calls)
+	}
+}
+
+func TestPlanMenuChanges(t *testing.T) {
+	var calls []string
+	sdk := newTestSDK(t, menuServer(t, liveMenuPayload, &calls))
+	spec, err := ParseMenuSpecYAML([]byte(strings.NewReplacer(
+		"赞一下我们", "点赞",
+		"tag_id: 2", "tag_id: 3",
+	).Replace(menuSpecYAML)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := sdk.PlanMenu(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MenuChangeAction{MenuChangeUpdate, MenuChangeDelete, MenuChangeCreate}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("PlanMenu() = %s", plan)
+	}
+	for i, change := range plan.Changes {
+		if change.Action != want[i] {
+			t.Fatalf("Changes[%d].Action = %s, want %s", i, change.Action, want[i])
+		}
+	}
+	if diff := plan.Changes[0].Diff; len(diff) != 1 || !strings.HasPrefix(diff[0], "~ button[1].sub_button[1]") {
+		t.Fatalf("default menu diff = %v", diff)
+	}
+	if plan.Changes[1].MenuID != "208396993" {
+		t.Fatalf("deleted menuid = %s", plan.Changes[1].MenuID)
+	}
+
+	calls = nil
+	if err = sdk.ApplyMenuPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	wantCalls := []string{"/cgi-bin/menu/create", "/cgi-bin/menu/delconditional", "/cgi-bin/menu/addconditional"}
+	if strings.Join(calls, ",") != strings.Join(wantCalls, ",") {
+		t.Fatalf("API calls = %v, want %v", calls, wantCalls)
+	}
+}
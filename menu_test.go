@@ -0,0 +1,119 @@
+package wechat
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestMatchRuleUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want MatchRule
+	}{
+		{
+			name: "字符串字段",
+			data: `{"tag_id":"100","client_platform_type":"2","language":"zh_CN"}`,
+			want: MatchRule{TagID: "100", ClientPlatformType: "2", Language: "zh_CN"},
+		},
+		{
+			name: "数字字段",
+			data: `{"tag_id":100,"sex":1,"client_platform_type":2}`,
+			want: MatchRule{TagID: "100", Sex: "1", ClientPlatformType: "2"},
+		},
+		{
+			name: "旧版group_id",
+			data: `{"group_id":101,"country":"中国"}`,
+			want: MatchRule{TagID: "101", Country: "中国"},
+		},
+		{
+			name: "tag_id优先于group_id",
+			data: `{"tag_id":"100","group_id":"101"}`,
+			want: MatchRule{TagID: "100"},
+		},
+		{
+			name: "空值",
+			data: `{"tag_id":null,"language":""}`,
+			want: MatchRule{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got MatchRule
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCurrentSelfMenuInfo(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"is_menu_open": 1,
+			"selfmenu_info": {"button": [
+				{"type": "click", "name": "今日歌曲", "key": "V1001_TODAY_MUSIC"},
+				{"name": "菜单", "sub_button": {"list": [
+					{"type": "view", "name": "搜索", "url": "http://www.soso.com/"},
+					{"type": "news", "name": "图文", "value": "KQb_w_Tiz-nSdVLoTV35Psmty8hGBulGhEdbb9SKs-o",
+						"news_info": {"list": [{"title": "MULTI_NEWS", "show_cover": 0, "content_url": "http://mp.weixin.qq.com/s"}]}}
+				]}}
+			]}
+		}`))
+	})
+	resp, err := sdk.GetCurrentSelfMenuInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buttons := resp.SelfMenuInfo.Button
+	if resp.IsMenuOpen != 1 || len(buttons) != 2 || len(buttons[0].SubButton) != 0 || len(buttons[1].SubButton) != 2 {
+		t.Fatalf("GetCurrentSelfMenuInfo() = %+v", resp)
+	}
+	if news := buttons[1].SubButton[1].NewsInfo; news == nil || news.List[0].Title != "MULTI_NEWS" {
+		t.Fatalf("news_info = %+v", news)
+	}
+}
+
+func TestMenuRoundTrip(t *testing.T) {
+	var created map[string]interface{}
+	menuExists := true
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/menu/get":
+			if !menuExists {
+				w.Write([]byte(`{"errcode":46003,"errmsg":"menu no exist"}`))
+				return
+			}
+			w.Write([]byte(liveMenuPayload))
+		case "/cgi-bin/menu/create":
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Error(err)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	})
+
+	live, err := sdk.GetMenu()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live.Menu.MenuID != "208396938" || live.ConditionalMenu[0].MatchRule.TagID != "2" {
+		t.Fatalf("GetMenu() = %+v", live)
+	}
+	// 查询得到的菜单可直接重新创建，菜单ID不会被提交
+	if err = sdk.CreateMenu(live.Menu); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := created["menuid"]; ok || len(created["button"].([]interface{})) != 2 {
+		t.Fatalf("CreateMenu() body = %v", created)
+	}
+
+	menuExists = false
+	if live, err = sdk.GetMenu(); err != nil || len(live.Menu.Button) != 0 {
+		t.Fatalf("GetMenu() without menu = %+v, %v", live, err)
+	}
+}
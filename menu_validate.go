@@ -0,0 +1,203 @@
+package wechat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 自定义菜单按钮类型
+const (
+	MenuTypeClick              = "click"                // 点击推事件
+	MenuTypeView               = "view"                 // 跳转URL
+	MenuTypeScanCodePush       = "scancode_push"        // 扫码推事件
+	MenuTypeScanCodeWaitMsg    = "scancode_waitmsg"     // 扫码推事件且弹出"消息接收中"提示框
+	MenuTypePicSysPhoto        = "pic_sysphoto"         // 弹出系统拍照发图
+	MenuTypePicPhotoOrAlbum    = "pic_photo_or_album"   // 弹出拍照或者相册发图
+	MenuTypePicWeixin          = "pic_weixin"           // 弹出微信相册发图器
+	MenuTypeLocationSelect     = "location_select"      // 弹出地理位置选择器
+	MenuTypeMediaID            = "media_id"             // 下发消息（除文本消息）
+	MenuTypeViewLimited        = "view_limited"         // 跳转图文消息URL
+	MenuTypeArticleID          = "article_id"           // 下发发布后的图文消息
+	MenuTypeArticleViewLimited = "article_view_limited" // 跳转发布后的图文消息URL
+	MenuTypeMiniProgram        = "miniprogram"          // 跳转小程序
+)
+
+// 自定义菜单的数量与长度限制
+const (
+	menuMaxButtons      = 3    // 一级菜单最多3个
+	menuMaxSubButtons   = 5    // 每个一级菜单最多包含5个二级菜单
+	menuMaxNameBytes    = 16   // 一级菜单标题最多16个字节
+	menuMaxSubNameBytes = 60   // 二级菜单标题最多60个字节
+	menuMaxKeyBytes     = 128  // 菜单KEY值最多128字节
+	menuMaxURLBytes     = 1024 // 网页链接最多1024字节
+)
+
+// ClickButton 构造点击推事件按钮
+func ClickButton(name, key string) MenuButton {
+	return MenuButton{Type: MenuTypeClick, Name: name, Key: key}
+}
+
+// ViewButton 构造跳转URL按钮
+func ViewButton(name, url string) MenuButton {
+	return MenuButton{Type: MenuTypeView, Name: name, Url: url}
+}
+
+// MiniProgramButton 构造跳转小程序按钮，url为不支持小程序的老版本客户端打开的网页
+func MiniProgramButton(name, url, appID, pagePath string) MenuButton {
+	return MenuButton{Type: MenuTypeMiniProgram, Name: name, Url: url, AppID: appID, PagePath: pagePath}
+}
+
+// EventButton 构造扫码、发图、选择地理位置等事件按钮，menuType如 MenuTypeScanCodePush、MenuTypePicWeixin、MenuTypeLocationSelect
+func EventButton(menuType, name, key string) MenuButton {
+	return MenuButton{Type: menuType, Name: name, Key: key}
+}
+
+// MediaButton 构造下发永久素材（media_id）或跳转图文消息（view_limited）按钮
+func MediaButton(menuType, name, mediaID string) MenuButton {
+	return MenuButton{Type: menuType, Name: name, MediaID: mediaID}
+}
+
+// ArticleButton 构造下发发布后的图文消息（article_id）或跳转发布后的图文消息（article_view_limited）按钮
+func ArticleButton(menuType, name, articleID string) MenuButton {
+	return MenuButton{Type: menuType, Name: name, ArticleID: articleID}
+}
+
+// ParentButton 构造包含二级菜单的一级菜单
+func ParentButton(name string, subButtons ...MenuButton) MenuButton {
+	return MenuButton{Name: name, SubButton: subButtons}
+}
+
+// MenuViolation 菜单中的一处不合法配置
+type MenuViolation struct {
+	Path    string // 出错位置，如 button[1].sub_button[0].key
+	Message string // 错误描述
+}
+
+// MenuValidationError 菜单校验错误，包含全部不合法的配置
+type MenuValidationError struct {
+	Violations []MenuViolation
+}
+
+func (e *MenuValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s：%s", violation.Path, violation.Message))
+	}
+	return "自定义菜单校验失败：" + strings.Join(messages, "；")
+}
+
+func (e *MenuValidationError) add(path, format string, args ...interface{}) {
+	e.Violations = append(e.Violations, MenuViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err 没有不合法配置时返回nil
+func (e *MenuValidationError) err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate 按官方文档的限制校验菜单，返回的错误为 *MenuValidationError，包含全部不合法的配置
+func (m Menu) Validate() error {
+	result := &MenuValidationError{}
+	validateMenuButtons(result, "", m.Button)
+	return result.err()
+}
+
+// Validate 校验个性化菜单的按钮和匹配规则
+func (m ConditionalMenu) Validate() error {
+	result := &MenuValidationError{}
+	validateConditionalMenu(result, "", m)
+	return result.err()
+}
+
+func validateConditionalMenu(result *MenuValidationError, prefix string, m ConditionalMenu) {
+	validateMenuButtons(result, prefix, m.Button)
+	if m.MatchRule == (MatchRule{}) {
+		result.add(prefix+"matchrule", "匹配规则至少需要填写一个字段")
+	}
+	switch m.MatchRule.ClientPlatformType {
+	case "", ClientPlatformIOS, ClientPlatformAndroid, ClientPlatformOthers:
+	default:
+		result.add(prefix+"matchrule.client_platform_type", "取值只能为1（IOS）、2（Android）、3（Others）")
+	}
+}
+
+func validateMenuButtons(result *MenuValidationError, prefix string, buttons []MenuButton) {
+	if len(buttons) == 0 {
+		result.add(prefix+"button", "至少需要1个一级菜单")
+	}
+	if len(buttons) > menuMaxButtons {
+		result.add(prefix+"button", "一级菜单最多%d个，当前为%d个", menuMaxButtons, len(buttons))
+	}
+	for i, button := range buttons {
+		path := fmt.Sprintf("%sbutton[%d]", prefix, i)
+		validateMenuName(result, path, button.Name, menuMaxNameBytes)
+
+		if len(button.SubButton) == 0 {
+			if button.Type == "" {
+				result.add(path+".type", "没有二级菜单时必须设置type")
+				continue
+			}
+			validateMenuButton(result, path, button)
+			continue
+		}
+
+		if len(button.SubButton) > menuMaxSubButtons {
+			result.add(path+".sub_button", "二级菜单最多%d个，当前为%d个", menuMaxSubButtons, len(button.SubButton))
+		}
+		for j, sub := range button.SubButton {
+			subPath := fmt.Sprintf("%s.sub_button[%d]", path, j)
+			validateMenuName(result, subPath, sub.Name, menuMaxSubNameBytes)
+			if len(sub.SubButton) > 0 {
+				result.add(subPath+".sub_button", "菜单最多只能有两级")
+			}
+			if sub.Type == "" {
+				result.add(subPath+".type", "二级菜单必须设置type")
+				continue
+			}
+			validateMenuButton(result, subPath, sub)
+		}
+	}
+}
+
+func validateMenuName(result *MenuValidationError, path, name string, maxBytes int) {
+	if name == "" {
+		result.add(path+".name", "菜单标题不能为空")
+	} else if len(name) > maxBytes {
+		result.add(path+".name", "菜单标题最多%d字节，当前为%d字节", maxBytes, len(name))
+	}
+}
+
+// validateMenuButton 按按钮类型校验必填字段
+func validateMenuButton(result *MenuValidationError, path string, button MenuButton) {
+	required := func(field, value string, maxBytes int) {
+		if value == "" {
+			result.add(path+"."+field, "%s类型的菜单必须设置%s", button.Type, field)
+		} else if maxBytes > 0 && len(value) > maxBytes {
+			result.add(path+"."+field, "最多%d字节，当前为%d字节", maxBytes, len(value))
+		}
+	}
+
+	switch button.Type {
+	case MenuTypeClick, MenuTypeScanCodePush, MenuTypeScanCodeWaitMsg, MenuTypePicSysPhoto,
+		MenuTypePicPhotoOrAlbum, MenuTypePicWeixin, MenuTypeLocationSelect:
+		required("key", button.Key, menuMaxKeyBytes)
+	case MenuTypeView:
+		required("url", button.Url, menuMaxURLBytes)
+	case MenuTypeMiniProgram:
+		// 不支持小程序的老版本客户端将打开url
+		required("url", button.Url, menuMaxURLBytes)
+		required("appid", button.AppID, 0)
+		required("pagepath", button.PagePath, 0)
+	case MenuTypeMediaID, MenuTypeViewLimited:
+		required("media_id", button.MediaID, 0)
+	case MenuTypeArticleID, MenuTypeArticleViewLimited:
+		required("article_id", button.ArticleID, 0)
+	case "text", "img", "voice", "video", "news":
+		result.add(path+".type", "%s类型的菜单只能在公众平台官网配置，无法通过接口创建", button.Type)
+	default:
+		result.add(path+".type", "不支持的菜单类型%q", button.Type)
+	}
+}
@@ -0,0 +1,123 @@
+package wechat
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMenuValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		menu  Menu
+		paths []string
+	}{
+		{
+			name: "合法菜单",
+			menu: Menu{Button: []MenuButton{
+				ClickButton("今日歌曲", "V1001_TODAY_MUSIC"),
+				ParentButton("菜单",
+					ViewButton("搜索", "http://www.soso.com/"),
+					MiniProgramButton("小程序", "http://mp.weixin.qq.com", "wx286b93c14bbf93aa", "pages/lunar/index"),
+					EventButton(MenuTypeLocationSelect, "发送位置", "rselfmenu_2_0"),
+					MediaButton(MenuTypeMediaID, "图片", "MEDIA_ID1"),
+					ArticleButton(MenuTypeArticleViewLimited, "文章", "ARTICLE_ID1"),
+				),
+			}},
+		},
+		{
+			name:  "没有按钮",
+			menu:  Menu{},
+			paths: []string{"button"},
+		},
+		{
+			name: "数量超出限制",
+			menu: Menu{Button: []MenuButton{
+				ClickButton("a", "a"), ClickButton("b", "b"), ClickButton("c", "c"),
+				ParentButton("d", ClickButton("1", "1"), ClickButton("2", "2"), ClickButton("3", "3"),
+					ClickButton("4", "4"), ClickButton("5", "5"), ClickButton("6", "6")),
+			}},
+			paths: []string{"button", "button[3].sub_button"},
+		},
+		{
+			name: "标题及必填字段",
+			menu: Menu{Button: []MenuButton{
+				ClickButton("一级菜单标题超出十六字节", "key"),
+				{Name: "无类型"},
+				ParentButton("菜单",
+					ViewButton("", ""),
+					MiniProgramButton("小程序", "http://mp.weixin.qq.com", "", "pages/index"),
+					MenuButton{Type: "news", Name: "图文"},
+					ParentButton("三级", ClickButton("x", "x")),
+				),
+			}},
+			paths: []string{
+				"button[0].name",
+				"button[1].type",
+				"button[2].sub_button[0].name",
+				"button[2].sub_button[0].url",
+				"button[2].sub_button[1].appid",
+				"button[2].sub_button[2].type",
+				"button[2].sub_button[3].sub_button",
+				"button[2].sub_button[3].type",
+			},
+		},
+		{
+			name:  "KEY超出长度",
+			menu:  Menu{Button: []MenuButton{ClickButton("点击", strings.Repeat("k", menuMaxKeyBytes+1))}},
+			paths: []string{"button[0].key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.menu.Validate()
+			if tt.paths == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var validationErr *MenuValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			var paths []string
+			for _, violation := range validationErr.Violations {
+				paths = append(paths, violation.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Fatalf("violation paths = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestConditionalMenuValidate(t *testing.T) {
+	menu := ConditionalMenu{Button: []MenuButton{ClickButton("点击", "key")}}
+	if err := menu.Validate(); err == nil || !strings.Contains(err.Error(), "matchrule") {
+		t.Fatalf("Validate() without match rule error = %v", err)
+	}
+	menu.MatchRule = MatchRule{ClientPlatformType: "4"}
+	if err := menu.Validate(); err == nil || !strings.Contains(err.Error(), "client_platform_type") {
+		t.Fatalf("Validate() with invalid platform error = %v", err)
+	}
+	menu.MatchRule = MatchRule{TagID: "100", ClientPlatformType: ClientPlatformIOS}
+	if err := menu.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateMenuValidatesBeforeRequest(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	})
+	var validationErr *MenuValidationError
+	if err := sdk.CreateMenu(Menu{}); !errors.As(err, &validationErr) {
+		t.Fatalf("CreateMenu() error = %v", err)
+	}
+	if _, err := sdk.AddConditionalMenu(ConditionalMenu{Button: []MenuButton{ClickButton("点击", "key")}}); !errors.As(err, &validationErr) {
+		t.Fatalf("AddConditionalMenu() error = %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package wechat
+
+import (
+	"context"
+	"sort"
+)
+
+// OpenIDMigrationStore 需要迁移openid的用户存储，可自行实现以对接数据库等
+type OpenIDMigrationStore interface {
+	// ListOpenIDs 按固定顺序返回cursor之后的最多limit个openid，以及下一页的游标
+	// cursor为空时从头开始，没有更多数据时返回空列表；next为空表示当前页为最后一页
+	ListOpenIDs(cursor string, limit int) (openIDs []string, next string, err error)
+	// ReplaceOpenID 将用户的旧openid替换为新openid
+	ReplaceOpenID(oldOpenID, newOpenID string) error
+}
+
+// MigratedOpenIDChecker 存储可选实现该接口，Run 会跳过已迁移过的openid，避免重复转换
+// 存储同时实现 Flusher 时，Run 会在每批处理完成后及结束时调用 Flush
+type MigratedOpenIDChecker interface {
+	IsMigrated(openID string) (bool, error)
+}
+
+// OpenIDMigrationProgress openid迁移进度
+type OpenIDMigrationProgress struct {
+	Cursor    string               // 已处理到的位置，传给 Run 可从此处继续
+	Processed int                  // 已处理的openid数
+	Skipped   int                  // 已迁移过而跳过的openid数
+	Migrated  int                  // 转换并替换成功的openid数
+	Failed    int                  // 转换失败的openid数
+	Failures  []ChangeOpenIDResult // 最近一批中转换失败的结果
+}
+
+// OpenIDMigrator 公众号迁移后的openid迁移工具，按批读取存储中的旧openid，转换后写回存储
+type OpenIDMigrator struct {
+	FromAppID  string                                 // 原公众号的appid
+	OnProgress func(progress OpenIDMigrationProgress) // 每处理完一批调用一次，可在此持久化Cursor以便中断后继续
+
+	sdk   *SDK
+	store OpenIDMigrationStore
+}
+
+// NewOpenIDMigrator 实例化openid迁移工具
+func NewOpenIDMigrator(sdk *SDK, fromAppID string, store OpenIDMigrationStore) *OpenIDMigrator {
+	return &OpenIDMigrator{FromAppID: fromAppID, sdk: sdk, store: store}
+}
+
+// Run 从cursor处开始迁移，cursor为空时从头开始；出错或ctx被取消时返回当前进度，可用其Cursor继续
+func (m *OpenIDMigrator) Run(ctx context.Context, cursor string) (*OpenIDMigrationProgress, error) {
+	progress, err := m.run(ctx, cursor)
+	// 出错时同样落盘，存储实现了 MigratedOpenIDChecker 时，已替换的openid在继续迁移时会被跳过
+	if flushErr := m.flush(); err == nil {
+		err = flushErr
+	}
+	return progress, err
+}
+
+func (m *OpenIDMigrator) run(ctx context.Context, cursor string) (*OpenIDMigrationProgress, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	progress := &OpenIDMigrationProgress{Cursor: cursor}
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		openIDs, next, err := m.store.ListOpenIDs(progress.Cursor, changeOpenIDLimit)
+		if err != nil {
+			return progress, err
+		}
+		if len(openIDs) == 0 {
+			return progress, nil
+		}
+
+		pending, err := m.pending(openIDs)
+		if err != nil {
+			return progress, err
+		}
+		var results []ChangeOpenIDResult
+		if len(pending) > 0 {
+			if results, err = m.sdk.ChangeOpenID(m.FromAppID, pending); err != nil {
+				return progress, err
+			}
+		}
+		progress.Skipped += len(openIDs) - len(pending)
+		progress.Failures = nil
+		for _, result := range results {
+			if result.ErrMsg != "" || result.NewOpenID == "" {
+				progress.Failed++
+				progress.Failures = append(progress.Failures, result)
+				continue
+			}
+			if err = m.store.ReplaceOpenID(result.OriOpenID, result.NewOpenID); err != nil {
+				return progress, err
+			}
+			progress.Migrated++
+		}
+		progress.Processed += len(openIDs)
+		if next != "" {
+			progress.Cursor = next
+		}
+
+		// 先落盘再通知，保证持久化的Cursor之前的数据都已写入存储
+		if err = m.flush(); err != nil {
+			return progress, err
+		}
+		if m.OnProgress != nil {
+			m.OnProgress(*progress)
+		}
+		// 没有下一页游标时视为已处理完毕
+		if next == "" {
+			return progress, nil
+		}
+	}
+}
+
+// pending 过滤掉存储已标记为迁移过的openid
+func (m *OpenIDMigrator) pending(openIDs []string) ([]string, error) {
+	checker, ok := m.store.(MigratedOpenIDChecker)
+	if !ok {
+		return openIDs, nil
+	}
+	pending := make([]string, 0, len(openIDs))
+	for _, openID := range openIDs {
+		migrated, err := checker.IsMigrated(openID)
+		if err != nil {
+			return nil, err
+		}
+		if !migrated {
+			pending = append(pending, openID)
+		}
+	}
+	return pending, nil
+}
+
+// flush 存储实现了 Flusher 时将数据落盘
+func (m *OpenIDMigrator) flush() error {
+	if flusher, ok := m.store.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// followerMigrationStore 将 FollowerStore 适配为 OpenIDMigrationStore
+// 从头迁移时按openid排序生成一次快照用于分页；替换出的新openid记录了 UserInfo.MigratedFrom，
+// 随存储一起持久化，进程重启后继续迁移时会被 IsMigrated 识别并跳过
+type followerMigrationStore struct {
+	store     FollowerStore
+	sortedIDs []string
+}
+
+// NewFollowerOpenIDMigrator 实例化迁移关注者存储中openid的迁移工具，可直接使用 FollowerMirror 的存储
+// 存储实现了 Flusher（如 FileFollowerStore）时，每批处理完成后会落盘
+func NewFollowerOpenIDMigrator(sdk *SDK, fromAppID string, store FollowerStore) *OpenIDMigrator {
+	return NewOpenIDMigrator(sdk, fromAppID, &followerMigrationStore{store: store})
+}
+
+// ListOpenIDs 按openid升序分页返回，游标为上一页最后一个openid
+func (f *followerMigrationStore) ListOpenIDs(cursor string, limit int) ([]string, string, error) {
+	if f.sortedIDs == nil || cursor == "" {
+		f.sortedIDs = f.sortedIDs[:0]
+		err := f.store.Range(func(info UserInfo) bool {
+			f.sortedIDs = append(f.sortedIDs, info.OpenID)
+			return true
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		sort.Strings(f.sortedIDs)
+	}
+
+	start := sort.SearchStrings(f.sortedIDs, cursor)
+	if start < len(f.sortedIDs) && f.sortedIDs[start] == cursor {
+		start++
+	}
+	var openIDs []string
+	for i := start; i < len(f.sortedIDs) && len(openIDs) < limit; i++ {
+		// 跳过生成快照后被删除或替换的openid
+		_, ok, err := f.store.Get(f.sortedIDs[i])
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			openIDs = append(openIDs, f.sortedIDs[i])
+		}
+	}
+	if len(openIDs) == 0 {
+		return nil, "", nil
+	}
+	return openIDs, openIDs[len(openIDs)-1], nil
+}
+
+// ReplaceOpenID 将用户的旧openid替换为新openid，并在新记录中标记迁移来源
+func (f *followerMigrationStore) ReplaceOpenID(oldOpenID, newOpenID string) error {
+	info, ok, err := f.store.Get(oldOpenID)
+	if err != nil || !ok {
+		return err
+	}
+	info.OpenID = newOpenID
+	info.MigratedFrom = oldOpenID
+	// 先写入新记录再删除旧记录，中断时最多保留一条可被跳过的旧记录
+	if err = f.store.Put(info); err != nil {
+		return err
+	}
+	return f.store.Delete(oldOpenID)
+}
+
+// IsMigrated 返回openid是否为迁移中替换出的新openid
+func (f *followerMigrationStore) IsMigrated(openID string) (bool, error) {
+	info, ok, err := f.store.Get(openID)
+	if err != nil {
+		return false, err
+	}
+	return ok && info.MigratedFrom != "", nil
+}
+
+// Flush 存储实现了 Flusher 时将数据落盘
+func (f *followerMigrationStore) Flush() error {
+	if flusher, ok := f.store.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
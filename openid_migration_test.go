@@ -0,0 +1,171 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// flushCountingStore 记录 Flush 调用次数的内存存储
+type flushCountingStore struct {
+	*MemoryFollowerStore
+	flushes int
+}
+
+func (f *flushCountingStore) Flush() error {
+	f.flushes++
+	return nil
+}
+
+// changeOpenIDServer 模拟openid转换接口，将 old- 前缀替换为 x- 前缀，并记录每个openid的请求次数
+func changeOpenIDServer(t *testing.T, requested map[string]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OpenIDList []string `json:"openid_list"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		var resp ChangeOpenIDResponse
+		for _, openID := range body.OpenIDList {
+			requested[openID]++
+			resp.ResultList = append(resp.ResultList, ChangeOpenIDResult{
+				OriOpenID: openID,
+				NewOpenID: "x-" + strings.TrimPrefix(openID, "old-"),
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func TestOpenIDMigratorRun(t *testing.T) {
+	requested := make(map[string]int)
+	sdk := newTestSDK(t, changeOpenIDServer(t, requested))
+	store := &flushCountingStore{MemoryFollowerStore: NewMemoryFollowerStore()}
+	for i := 0; i < 250; i++ {
+		store.Put(UserInfo{Subscribe: 1, OpenID: fmt.Sprintf("old-%03d", i)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	migrator := NewFollowerOpenIDMigrator(sdk, "from-appid", store)
+	migrator.OnProgress = func(progress OpenIDMigrationProgress) {
+		// 处理完第一批后中断
+		cancel()
+	}
+	progress, err := migrator.Run(ctx, "")
+	if err != context.Canceled {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if progress.Migrated != 100 || progress.Cursor != "old-099" {
+		t.Fatalf("Run() progress = %+v", progress)
+	}
+	if store.flushes != 2 {
+		t.Fatalf("Flush called %d times, want 2", store.flushes)
+	}
+
+	// 从头重新执行时跳过已迁移的openid，且替换出的新openid不会被再次转换
+	migrator.OnProgress = nil
+	progress, err = migrator.Run(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Migrated != 150 || progress.Skipped != 100 || progress.Failed != 0 {
+		t.Fatalf("Run() progress = %+v", progress)
+	}
+	assertRequestedOnce(t, requested, 250)
+	info, ok, _ := store.Get("x-249")
+	if !ok || info.MigratedFrom != "old-249" {
+		t.Fatalf("last openid was not replaced: %+v", info)
+	}
+	if _, ok, _ = store.Get("old-249"); ok {
+		t.Fatal("old openid was not deleted")
+	}
+}
+
+func TestOpenIDMigratorResumeAfterRestart(t *testing.T) {
+	requested := make(map[string]int)
+	sdk := newTestSDK(t, changeOpenIDServer(t, requested))
+	path := filepath.Join(t.TempDir(), "followers.jsonl")
+	store, err := NewFileFollowerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 250; i++ {
+		store.Put(UserInfo{Subscribe: 1, OpenID: fmt.Sprintf("old-%03d", i)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	migrator := NewFollowerOpenIDMigrator(sdk, "from-appid", store)
+	migrator.OnProgress = func(progress OpenIDMigrationProgress) {
+		// 处理完第一批后中断，模拟进程退出
+		cancel()
+	}
+	if _, err = migrator.Run(ctx, ""); err != context.Canceled {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+
+	// 重新加载文件，已替换的openid仍会被识别并跳过
+	if store, err = NewFileFollowerStore(path); err != nil {
+		t.Fatal(err)
+	}
+	progress, err := NewFollowerOpenIDMigrator(sdk, "from-appid", store).Run(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Migrated != 150 || progress.Skipped != 100 || progress.Failed != 0 || progress.Processed != 250 {
+		t.Fatalf("Run() progress = %+v", progress)
+	}
+	assertRequestedOnce(t, requested, 250)
+}
+
+// assertRequestedOnce 检查每个旧openid都只被转换了一次
+func assertRequestedOnce(t *testing.T, requested map[string]int, want int) {
+	t.Helper()
+	if len(requested) != want {
+		t.Fatalf("requested %d openids, want %d", len(requested), want)
+	}
+	for openID, count := range requested {
+		if count != 1 || !strings.HasPrefix(openID, "old-") {
+			t.Fatalf("openid %s requested %d times", openID, count)
+		}
+	}
+}
+
+func TestFollowerMigrationStoreListOpenIDs(t *testing.T) {
+	store := NewMemoryFollowerStore()
+	store.Put(UserInfo{OpenID: "a"})
+	store.Put(UserInfo{OpenID: "c"})
+	migration := &followerMigrationStore{store: store}
+	list := func(cursor string) []string {
+		openIDs, _, err := migration.ListOpenIDs(cursor, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return openIDs
+	}
+	if got := list(""); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Fatalf("ListOpenIDs() = %v", got)
+	}
+
+	// 分页期间删除或替换的openid不再返回，快照之后新增的openid在下一次从头迁移时出现
+	store.Delete("c")
+	store.Put(UserInfo{OpenID: "b"})
+	if got := list("a"); len(got) != 0 {
+		t.Fatalf("ListOpenIDs() = %v", got)
+	}
+	migration.ReplaceOpenID("a", "d")
+	if got := list(""); !reflect.DeepEqual(got, []string{"b", "d"}) {
+		t.Fatalf("ListOpenIDs() = %v", got)
+	}
+	if migrated, _ := migration.IsMigrated("d"); !migrated {
+		t.Fatal("replaced openid was not marked as migrated")
+	}
+	if migrated, _ := migration.IsMigrated("b"); migrated {
+		t.Fatal("openid b was marked as migrated")
+	}
+}
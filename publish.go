@@ -0,0 +1,280 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPublishFailed 发布任务未成功，可通过返回的 PublishEventInfo 查看发布状态及失败的文章
+var ErrPublishFailed = errors.New("发布失败")
+
+const (
+	// batchGetPublishedLimit 获取已发布列表时单次请求的数量上限
+	batchGetPublishedLimit = 20
+	// publishEventBufferTTL 尚无等待者的发布事件的保留时长
+	publishEventBufferTTL = 10 * time.Minute
+)
+
+// publishPollInterval PublishAndWait 轮询发布状态的间隔
+var publishPollInterval = 5 * time.Second
+
+// SubmitPublish 发布草稿，返回发布任务的publish_id，发布结果通过 PUBLISHJOBFINISH 事件推送或 GetPublishStatus 查询
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Publish/Publish.html
+func (s *SDK) SubmitPublish(mediaID string) (string, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/freepublish/submit?access_token=%s", s.AccessToken)
+
+	var responseJson SubmitPublishResponse
+	if err := postJSON(url, map[string]interface{}{"media_id": mediaID}, &responseJson); err != nil {
+		return "", err
+	}
+	if responseJson.Errcode != 0 {
+		return "", ErrorHandler(ErrSubmitPublish, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.PublishID, nil
+}
+
+// GetPublishStatus 查询发布状态
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Publish/Get_status.html
+func (s *SDK) GetPublishStatus(publishID string) (*PublishEventInfo, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/freepublish/get?access_token=%s", s.AccessToken)
+
+	var responseJson GetPublishStatusResponse
+	if err := postJSON(url, map[string]interface{}{"publish_id": publishID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetPublishStatus, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson.PublishEventInfo, nil
+}
+
+// DeletePublish 删除已发布的文章，index为要删除的文章编号，第一篇为1，为0时删除全部文章，此操作无法撤销
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Publish/Delete_posts.html
+func (s *SDK) DeletePublish(articleID string, index int) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"article_id": articleID,
+		"index":      index,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/freepublish/delete?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDeletePublish, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// GetPublishedArticle 通过article_id获取已发布的文章
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Publish/Get_article_from_id.html
+func (s *SDK) GetPublishedArticle(articleID string) ([]Article, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/freepublish/getarticle?access_token=%s", s.AccessToken)
+
+	var responseJson GetDraftResponse
+	if err := postJSON(url, map[string]interface{}{"article_id": articleID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetPublishedArticle, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.NewsItem, nil
+}
+
+// BatchGetPublished 获取成功发布的列表，offset从0开始，count取值在1到20之间，noContent为true时不返回文章的content字段
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Publish/Get_publication_records.html
+func (s *SDK) BatchGetPublished(offset, count int, noContent bool) (*BatchGetPublishedResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"offset":     offset,
+		"count":      count,
+		"no_content": boolToInt(noContent),
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/freepublish/batchget?access_token=%s", s.AccessToken)
+
+	var responseJson BatchGetPublishedResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrBatchGetPublished, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// PublishedIterator 基于offset分页的已发布文章迭代器，每次拉取20个
+//
+//	it := sdk.Published(ctx, 0, true)
+//	for it.Next() {
+//		fmt.Println(it.Item().ArticleID)
+//	}
+//	if err := it.Err(); err != nil {
+//		// 可保存 it.Offset() 以便稍后继续
+//	}
+type PublishedIterator struct {
+	offsetPager
+	fetch func(offset int) (*BatchGetPublishedResponse, error)
+	page  []PublishedItem
+}
+
+// Published 返回全部已发布文章的迭代器，自动处理分页，offset为起始位置，noContent为true时不返回文章的content字段
+func (s *SDK) Published(ctx context.Context, offset int, noContent bool) *PublishedIterator {
+	return &PublishedIterator{
+		offsetPager: newOffsetPager(ctx, offset),
+		fetch: func(offset int) (*BatchGetPublishedResponse, error) {
+			return s.BatchGetPublished(offset, batchGetPublishedLimit, noContent)
+		},
+	}
+}
+
+// Next 移动到下一个已发布的图文，没有更多数据、出错或ctx被取消时返回false
+func (it *PublishedIterator) Next() bool {
+	return it.next(func(offset int) (int, int, error) {
+		resp, err := it.fetch(offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		it.page = resp.Item
+		return len(resp.Item), resp.TotalCount, nil
+	})
+}
+
+// Item 返回当前已发布的图文
+func (it *PublishedIterator) Item() *PublishedItem {
+	return &it.page[it.index-1]
+}
+
+// publishWaiters 等待发布结果的任务，按publish_id分发 PUBLISHJOBFINISH 事件
+// 事件可能早于 SubmitPublish 返回到达，尚无等待者的事件会暂存一段时间
+type publishWaiters struct {
+	mutex    sync.Mutex
+	waiters  map[string]chan *PublishEventInfo
+	finished map[string]bufferedPublishEvent
+}
+
+// bufferedPublishEvent 暂存的发布事件
+type bufferedPublishEvent struct {
+	info       *PublishEventInfo
+	receivedAt time.Time
+}
+
+func newPublishWaiters() *publishWaiters {
+	return &publishWaiters{
+		waiters:  make(map[string]chan *PublishEventInfo),
+		finished: make(map[string]bufferedPublishEvent),
+	}
+}
+
+// add 登记等待者，已暂存该任务的事件时立即送达
+func (p *publishWaiters) add(publishID string) chan *PublishEventInfo {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	ch := make(chan *PublishEventInfo, 1)
+	if event, ok := p.finished[publishID]; ok {
+		delete(p.finished, publishID)
+		ch <- event.info
+	}
+	p.waiters[publishID] = ch
+	return ch
+}
+
+func (p *publishWaiters) remove(publishID string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.waiters, publishID)
+}
+
+func (p *publishWaiters) notify(info *PublishEventInfo) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if ch, ok := p.waiters[info.PublishID]; ok {
+		select {
+		case ch <- info:
+		default:
+		}
+		return
+	}
+
+	now := time.Now()
+	for publishID, event := range p.finished {
+		if now.Sub(event.receivedAt) > publishEventBufferTTL {
+			delete(p.finished, publishID)
+		}
+	}
+	p.finished[info.PublishID] = bufferedPublishEvent{info: info, receivedAt: now}
+}
+
+// PublishAndWait 发布草稿并等待发布完成
+// 发布结果优先取自 PUBLISHJOBFINISH 事件推送（需通过 HandleWeChatMessage 接收消息），同时每5秒轮询一次发布状态
+// 查询发布状态出错时在下次轮询重试，直到ctx被取消；发布状态不为成功时返回发布结果及 ErrPublishFailed
+func (s *SDK) PublishAndWait(ctx context.Context, mediaID string) (*PublishEventInfo, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.publishOnce.Do(func() {
+		s.publishes = newPublishWaiters()
+		s.addHook(func(msg *Message) {
+			if msg.Type == EventMessage && msg.Event == EventPublishJobFinish && msg.PublishEvent != nil {
+				s.publishes.notify(msg.PublishEvent)
+			}
+		})
+	})
+
+	publishID, err := s.SubmitPublish(mediaID)
+	if err != nil {
+		return nil, err
+	}
+	finished := s.publishes.add(publishID)
+	defer s.publishes.remove(publishID)
+
+	ticker := time.NewTicker(publishPollInterval)
+	defer ticker.Stop()
+	var pollErr error
+	for {
+		select {
+		case info := <-finished:
+			return publishResult(info)
+		case <-ticker.C:
+			info, err := s.GetPublishStatus(publishID)
+			if err != nil {
+				pollErr = err
+				continue
+			}
+			if info.PublishStatus != PublishStatusPublishing {
+				info.PublishID = publishID
+				return publishResult(info)
+			}
+		case <-ctx.Done():
+			if pollErr != nil {
+				return nil, fmt.Errorf("%w，最近一次查询发布状态失败：%v", ctx.Err(), pollErr)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// publishResult 发布状态不为成功时返回 ErrPublishFailed
+func publishResult(info *PublishEventInfo) (*PublishEventInfo, error) {
+	if info.PublishStatus != PublishStatusSuccess {
+		return info, fmt.Errorf("%w：发布状态%d，失败的文章编号%v", ErrPublishFailed, info.PublishStatus, info.FailIdx)
+	}
+	return info, nil
+}
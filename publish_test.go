@@ -0,0 +1,123 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPublishAndWaitEventBeforeSubmitReturns(t *testing.T) {
+	var sdk *SDK
+	sdk = newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/freepublish/submit":
+			// 发布事件在提交接口返回前到达
+			sdk.runHooks(&Message{Type: EventMessage, Event: EventPublishJobFinish, PublishEvent: &PublishEventInfo{
+				PublishID:     "100",
+				PublishStatus: PublishStatusSuccess,
+				ArticleID:     "article",
+			}})
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","publish_id":"100"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	info, err := sdk.PublishAndWait(ctx, "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ArticleID != "article" {
+		t.Fatalf("PublishAndWait() = %+v", info)
+	}
+}
+
+func TestPublishAndWaitPolling(t *testing.T) {
+	interval := publishPollInterval
+	publishPollInterval = 10 * time.Millisecond
+	defer func() { publishPollInterval = interval }()
+
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  error
+	}{
+		{
+			name:     "查询出错后重试",
+			statuses: []string{`{"errcode":-1,"errmsg":"system error"}`, `{"publish_status":1}`, `{"publish_status":0,"article_id":"article"}`},
+		},
+		{
+			name:     "发布失败",
+			statuses: []string{`{"publish_status":2,"fail_idx":[1]}`},
+			wantErr:  ErrPublishFailed,
+		},
+		{
+			name:     "一直查询出错",
+			statuses: []string{`{"errcode":-1,"errmsg":"system error"}`},
+			wantErr:  context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var polls int32
+			sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/cgi-bin/freepublish/submit":
+					w.Write([]byte(`{"errcode":0,"errmsg":"ok","publish_id":"100"}`))
+				case "/cgi-bin/freepublish/get":
+					n := int(atomic.AddInt32(&polls, 1))
+					if n > len(tt.statuses) {
+						n = len(tt.statuses)
+					}
+					w.Write([]byte(tt.statuses[n-1]))
+				}
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			info, err := sdk.PublishAndWait(ctx, "media")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PublishAndWait() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (info.ArticleID != "article" || info.PublishID != "100") {
+				t.Fatalf("PublishAndWait() = %+v", info)
+			}
+		})
+	}
+}
+
+func TestPublished(t *testing.T) {
+	var offsets []int
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var data struct {
+			Offset int `json:"offset"`
+		}
+		json.NewDecoder(r.Body).Decode(&data)
+		offsets = append(offsets, data.Offset)
+		if data.Offset > 0 {
+			w.Write([]byte(`{"errcode":-1,"errmsg":"system error"}`))
+			return
+		}
+		w.Write([]byte(`{"total_count":3,"item_count":2,"item":[{"article_id":"a1"},{"article_id":"a2"}]}`))
+	})
+
+	var articleIDs []string
+	it := sdk.Published(context.Background(), 0, true)
+	for it.Next() {
+		articleIDs = append(articleIDs, it.Item().ArticleID)
+	}
+	var apiErr *APIError
+	if !errors.As(it.Err(), &apiErr) || apiErr.Errcode != -1 || it.Offset() != 2 {
+		t.Fatalf("Err() = %v, Offset() = %d", it.Err(), it.Offset())
+	}
+	if fmt.Sprint(articleIDs) != "[a1 a2]" || fmt.Sprint(offsets) != "[0 2]" {
+		t.Fatalf("articles %v, offsets %v", articleIDs, offsets)
+	}
+}
@@ -2,17 +2,22 @@ package wechat
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -42,11 +47,257 @@ func (s *SDK) checkAccessToken() error {
 	return nil
 }
 
+// postJSON 以JSON格式发送POST请求，并将响应解析到result
+func postJSON(url string, data interface{}, result interface{}) error {
+	resp, err := postJSONResponse(url, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+// postJSONResponse 以JSON格式发送POST请求，返回未读取的响应，用于响应可能是文件的接口，由调用方关闭响应
+func postJSONResponse(url string, data interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	return client.Do(request)
+}
+
+// getJSON 发送GET请求，并将响应解析到result
+func getJSON(url string, result interface{}) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+// multipartFile multipart表单中的文件字段
+type multipartFile struct {
+	field       string    // 表单字段名，如 media
+	filename    string    // 文件名，微信根据扩展名识别文件格式
+	contentType string    // 文件的Content-Type，为空时根据扩展名推断
+	reader      io.Reader // 文件内容
+	size        int64     // 文件大小，未知时为-1
+}
+
+// postMultipart 以multipart表单格式上传文件及其他字段，并将响应解析到result
+// 表单通过 io.Pipe 边生成边发送，不会将文件读入内存；文件大小已知时设置Content-Length
+// ctx被取消时立即返回，即使文件的reader仍阻塞在读取中
+func postMultipart(url string, file multipartFile, fields map[string]string, o *uploadOptions, result interface{}) error {
+	contentType := file.contentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(file.filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(file.field), escapeQuotes(file.filename)))
+	header.Set("Content-Type", contentType)
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// writeForm 写入除文件内容以外的表单，content为空时只用于计算表单的长度
+	writeForm := func(writer *multipart.Writer, content io.Reader) error {
+		for _, name := range names {
+			if err := writer.WriteField(name, fields[name]); err != nil {
+				return err
+			}
+		}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if content != nil {
+			if _, err = io.Copy(part, content); err != nil {
+				return err
+			}
+		}
+		return writer.Close()
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+	var contentLength int64 = -1
+	if file.size >= 0 {
+		counter := &countingWriter{}
+		dryRun := multipart.NewWriter(counter)
+		if err := dryRun.SetBoundary(writer.Boundary()); err != nil {
+			return err
+		}
+		if err := writeForm(dryRun, nil); err != nil {
+			return err
+		}
+		contentLength = counter.n + file.size
+	}
+
+	content := file.reader
+	if o.progress != nil {
+		content = &progressReader{reader: file.reader, total: file.size, progress: o.progress}
+	}
+	writeErr := make(chan error, 1)
+	go func() {
+		err := writeForm(writer, content)
+		pipeWriter.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	request, err := http.NewRequestWithContext(o.ctx, "POST", url, pipeReader)
+	if err != nil {
+		pipeReader.Close()
+		return err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.ContentLength = contentLength
+
+	// ctx被取消时关闭管道，避免发送请求体时阻塞在未响应取消的reader上
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-o.ctx.Done():
+			pipeReader.CloseWithError(o.ctx.Err())
+		case <-finished:
+		}
+	}()
+
+	client := &http.Client{}
+	resp, err := client.Do(request)
+	// 关闭管道，确保写入协程退出；上传被取消或读取文件出错时优先返回对应的错误
+	pipeReader.Close()
+	var werr error
+	select {
+	case werr = <-writeErr:
+	case <-o.ctx.Done():
+		// 文件的reader阻塞且不响应取消时不再等待，写入协程在reader返回后退出
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return o.ctx.Err()
+	}
+	if err != nil && o.ctx.Err() != nil {
+		return o.ctx.Err()
+	}
+	if werr != nil && !errors.Is(werr, io.ErrClosedPipe) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return werr
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+// countingWriter 只统计写入的字节数
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// progressReader 读取文件内容时回调上传进度
+type progressReader struct {
+	reader   io.Reader
+	uploaded int64
+	total    int64
+	progress func(uploaded, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.uploaded += int64(n)
+		r.progress(r.uploaded, r.total)
+	}
+	return n, err
+}
+
+// readerSize 返回reader剩余内容的大小，无法获取时返回-1
+func readerSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes 转义表单字段名及文件名中的引号
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
 // RegisterHandler 注册消息处理方法
 func (s *SDK) RegisterHandler(msgType MessageType, handler MessageHandler) {
 	s.handlers[msgType] = handler
 }
 
+// addHook 注册内部钩子，SDK内置的跟踪、同步等功能通过钩子观察推送的消息和事件
+func (s *SDK) addHook(hook func(msg *Message)) {
+	s.hooksMutex.Lock()
+	defer s.hooksMutex.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// runHooks 依次调用内部钩子
+func (s *SDK) runHooks(msg *Message) {
+	s.hooksMutex.RLock()
+	hooks := s.hooks
+	s.hooksMutex.RUnlock()
+	for _, hook := range hooks {
+		hook(msg)
+	}
+}
+
 // 解析微信xml消息到结构体
 func parseWeChatMessage(data []byte) (*XMLMessage, error) {
 	var msg XMLMessage
@@ -69,6 +320,7 @@ func (s *SDK) HandleWeChatMessage(data []byte, w http.ResponseWriter) {
 	genericMsg := &Message{
 		ToUserName:   msg.ToUserName,
 		FromUserName: msg.FromUserName,
+		sdk:          s,
 	}
 
 	switch msg.MsgType {
@@ -83,12 +335,33 @@ func (s *SDK) HandleWeChatMessage(data []byte, w http.ResponseWriter) {
 	case "event":
 		genericMsg.Type = EventMessage
 		genericMsg.Event = msg.Event
+		genericMsg.MsgID = msg.MsgID
+		genericMsg.Status = msg.Status
+		switch msg.Event {
+		case EventSubscribeMsgPopup:
+			genericMsg.SubscribeMsgEvents = msg.SubscribeMsgPopupEvent
+		case EventSubscribeMsgChange:
+			genericMsg.SubscribeMsgEvents = msg.SubscribeMsgChangeEvent
+		case EventSubscribeMsgSent:
+			genericMsg.SubscribeMsgEvents = msg.SubscribeMsgSentEvent
+		case EventMassSendJobFinish:
+			result := msg.MassSendJobResult
+			genericMsg.MassSendJob = &result
+		case EventPublishJobFinish:
+			genericMsg.PublishEvent = msg.PublishEventInfo
+		}
 	// 添加其他消息类型的转换
 	default:
 		// 处理未知消息类型
 		return
 	}
 
+	// 关注状态变化时先使用户缓存失效，保证钩子和处理器读取到最新的用户信息
+	if msg.Event == EventSubscribe || msg.Event == EventUnsubscribe {
+		s.invalidateUsers(msg.FromUserName)
+	}
+	s.runHooks(genericMsg)
+
 	// 调用对应类型的处理器
 	if handler, ok := s.handlers[genericMsg.Type]; ok {
 		handler(genericMsg, w)
@@ -106,6 +379,19 @@ func (s *SDK) BuildTextResponse(toUser, fromUser, content string) string {
 </xml>`, toUser, fromUser, time.Now().Unix(), content)
 }
 
+// BuildImageResponse 构造被动回复图片消息xml，mediaID可通过上传临时素材获得
+func (s *SDK) BuildImageResponse(toUser, fromUser, mediaID string) string {
+	return fmt.Sprintf(`<xml>
+<ToUserName><![CDATA[%s]]></ToUserName>
+<FromUserName><![CDATA[%s]]></FromUserName>
+<CreateTime>%d</CreateTime>
+<MsgType><![CDATA[image]]></MsgType>
+<Image>
+<MediaId><![CDATA[%s]]></MediaId>
+</Image>
+</xml>`, toUser, fromUser, time.Now().Unix(), mediaID)
+}
+
 // SendTextMessage 发送文本消息
 // 官方文档地址：https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Service_Center_messages.html#%E5%AE%A2%E6%9C%8D%E6%8E%A5%E5%8F%A3-%E5%8F%91%E6%B6%88%E6%81%AF
 func (s *SDK) SendTextMessage(toUser, content string) error {
@@ -269,23 +555,23 @@ func (s *SDK) NewTemMessage(touser, templateID, url, appID, appPagePath, clientM
 	}
 }
 
-// SendTempMessage 发送模版消息
+// SendTempMessage 发送模版消息，返回消息ID，可用于查询送达状态
 // 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Template_Message_Interface.html
-func (s *SDK) SendTempMessage(message *TempMessage) error {
+func (s *SDK) SendTempMessage(message *TempMessage) (int64, error) {
 	if err := s.checkAccessToken(); err != nil {
-		return err
+		return 0, err
 	}
 	// 将消息数据序列化为JSON
 	jsonData, err := json.Marshal(message)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 创建请求
 	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/template/send?access_token=%s", s.AccessToken)
 	request, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 设置请求头
@@ -295,27 +581,31 @@ func (s *SDK) SendTempMessage(message *TempMessage) error {
 	client := &http.Client{}
 	resp, err := client.Do(request)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 解析响应
 	var responseJson SendTempMessageResponse
 	if err = json.Unmarshal(body, &responseJson); err != nil {
-		return err
+		return 0, err
 	}
 
-	if responseJson.Errcode == 0 {
-		return nil
+	if responseJson.Errcode != 0 {
+		return 0, ErrorHandler(ErrSendTempMessage, responseJson.Errmsg, responseJson.Errcode)
 	}
 
-	return ErrorHandler(ErrSendTempMessage, responseJson.Errmsg, responseJson.Errcode)
+	// 开启送达跟踪时记录本次发送，消息已发送成功，记录失败时通过 OnError 通知
+	if s.tracker != nil {
+		s.tracker.record(message, responseJson.MsgID)
+	}
+	return responseJson.MsgID, nil
 }
 
 // GetUserList 获取用户列表
@@ -353,15 +643,42 @@ func (s *SDK) GetUserList(nextOpenID string) (*GetUserListResponse, error) {
 	return &responseJson, nil
 }
 
+// Followers 返回全部关注者openid的迭代器，自动处理next_openid分页
+// startOpenID为空时从头开始，传入之前保存的 Cursor() 可从中断处继续
+func (s *SDK) Followers(ctx context.Context, startOpenID string) *OpenIDIterator {
+	return newOpenIDIterator(ctx, startOpenID, func(nextOpenID string) ([]string, string, error) {
+		resp, err := s.GetUserList(nextOpenID)
+		if err != nil {
+			return nil, "", err
+		}
+		if resp.Count == 0 {
+			return nil, "", nil
+		}
+		return resp.Data.OpenID, resp.NextOpenID, nil
+	})
+}
+
 // GetUserInfo 获取用户基本信息
 // 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Get_users_basic_information_UnionID.html#UinonId
 func (s *SDK) GetUserInfo(openID string) (*GetUserInfoResponse, error) {
+	return s.GetUserInfoWithLang(openID, LangZhCN)
+}
+
+// GetUserInfoWithLang 获取用户基本信息，lang为返回国家地区语言版本，如 LangZhCN、LangZhTW、LangEn
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Get_users_basic_information_UnionID.html#UinonId
+func (s *SDK) GetUserInfoWithLang(openID, lang string) (*GetUserInfoResponse, error) {
+	cache := s.cache()
+	if cache != nil {
+		if info, ok := cache.get(openID, lang); ok {
+			return &GetUserInfoResponse{UserInfo: info}, nil
+		}
+	}
 	if err := s.checkAccessToken(); err != nil {
 		return nil, err
 	}
 	// 接口地址
-	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/user/info?access_token=%s&openid=%s&lang=zh_CN",
-		s.AccessToken, openID)
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/user/info?access_token=%s&openid=%s&lang=%s",
+		s.AccessToken, openID, lang)
 
 	// 发送GET请求
 	resp, err := http.Get(url)
@@ -385,6 +702,9 @@ func (s *SDK) GetUserInfo(openID string) (*GetUserInfoResponse, error) {
 	if responseJson.Errcode != 0 {
 		return nil, ErrorHandler(ErrGetUserInfo, responseJson.Errmsg, responseJson.Errcode)
 	}
+	if cache != nil {
+		cache.set(responseJson.UserInfo, lang)
+	}
 	return &responseJson, nil
 }
 
@@ -421,145 +741,83 @@ func (s *SDK) GetWebAuthAccessToken(code string) (*GetWebAuthAccessTokenResponse
 	return &responseJson, nil
 }
 
-// DownloadAmrVoiceByMediaID 通过获取临时素材接口下载amr格式音频到指定路径
+// DownloadAmrVoiceByMediaID 通过获取临时素材接口下载amr格式音频到指定路径，下载失败时不会生成文件
 // 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_temporary_materials.html
 func (s *SDK) DownloadAmrVoiceByMediaID(mediaID, path string) error {
-	if err := s.checkAccessToken(); err != nil {
-		return err
-	}
-	// 接口地址
-	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/media/get?access_token=%s&media_id=%s",
-		s.AccessToken, mediaID)
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
 	// 确保目录存在
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	out, err := os.Create(path)
+	// 先写入临时文件，下载成功后再重命名
+	out, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	defer os.Remove(out.Name())
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if _, err = s.DownloadMedia(mediaID, out); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), path)
 }
 
 // DownloadAmrVoiceByMediaIDAndReturnBase64 通过获取临时素材接口下载amr格式音频，并返回Base64编码字符串
 // 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_temporary_materials.html
 func (s *SDK) DownloadAmrVoiceByMediaIDAndReturnBase64(mediaID string) (string, error) {
-	if err := s.checkAccessToken(); err != nil {
-		return "", err
-	}
-	// 接口地址
-	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/media/get?access_token=%s&media_id=%s",
-		s.AccessToken, mediaID)
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	var buf bytes.Buffer
+	if _, err := s.DownloadMedia(mediaID, &buf); err != nil {
 		return "", err
 	}
-
-	base64Str := base64.StdEncoding.EncodeToString(data)
-	return base64Str, nil
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
 }
 
-// AddMaterial 新增永久素材
+// AddMaterial 新增永久素材，fileUrl为素材的下载地址，上传时使用地址中的文件名
+// 视频素材需要提交描述，请使用 UploadVideoMaterial
 // 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html
-func (s *SDK) AddMaterial(mediaType, fileUrl string) (string, error) {
-	if err := s.checkAccessToken(); err != nil {
-		return "", err
+func (s *SDK) AddMaterial(mediaType, fileUrl string, opts ...UploadOption) (*AddMediaResponse, error) {
+	request, err := http.NewRequestWithContext(newUploadOptions(opts).ctx, "GET", fileUrl, nil)
+	if err != nil {
+		return nil, err
 	}
-	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/material/add_material?access_token=%s&type=%s",
-		s.AccessToken, mediaType)
-
-	resp, err := http.Get(fileUrl)
+	resp, err := http.DefaultClient.Do(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to download file: %v", err)
+		return nil, fmt.Errorf("failed to download file: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download file: status code %d", resp.StatusCode)
-	}
-
-	fileBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read downloaded file: %v", err)
+		return nil, fmt.Errorf("failed to download file: status code %d", resp.StatusCode)
 	}
 
-	// Create a buffer to hold the form data
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	part, err := writer.CreateFormFile("media", "test.jpg")
-	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %v", err)
+	// 边下载边上传，不会将文件读入内存
+	contentType := resp.Header.Get("Content-Type")
+	file := multipartFile{
+		field:       "media",
+		filename:    filenameFromURL(fileUrl, contentType),
+		contentType: contentType,
+		reader:      resp.Body,
+		size:        resp.ContentLength,
 	}
-
-	// 将文件字节流写入表单
-	_, err = io.Copy(part, bytes.NewReader(fileBytes))
-	if err != nil {
-		return "", fmt.Errorf("failed to copy file content: %v", err)
-	}
-
-	// 关闭multipart写入器，最终生成整个表单数据
-	err = writer.Close()
-	if err != nil {
-		return "", fmt.Errorf("failed to close multipart writer: %v", err)
-	}
-
-	// 创建HTTP POST请求
-	req, err := http.NewRequest("POST", url, body)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-	// 设置multipart表单的Content-Type
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	// 发送请求
-	client := &http.Client{}
-	resp2, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp2.Body.Close()
-
-	// 读取响应体
-	respBody, err := io.ReadAll(resp2.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
-	}
-	var response AddMediaResponse
-	if err = json.Unmarshal(respBody, &response); err != nil {
-		return "", err
-	}
-
-	if response.Errcode != 0 {
-		return "", fmt.Errorf(response.Errmsg)
-	}
-
-	return response.MediaId, nil
+	return s.addMaterial(mediaType, file, nil, opts)
 }
 
-// CreateMenu 创建自定义菜单
+// CreateMenu 创建自定义菜单，提交前会先调用 Validate 校验菜单
 // 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Creating_Custom-Defined_Menu.html
 func (s *SDK) CreateMenu(menu Menu) error {
+	if err := menu.Validate(); err != nil {
+		return err
+	}
 	if err := s.checkAccessToken(); err != nil {
 		return err
 	}
+	// 查询得到的菜单ID不需要提交
+	menu.MenuID = ""
 	// 将消息数据序列化为JSON
 	jsonData, err := json.Marshal(menu)
 	if err != nil {
@@ -0,0 +1,183 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newTestSDK 返回已有access_token的SDK，测试期间所有HTTP请求都会转发到handler
+func newTestSDK(t *testing.T, handler http.HandlerFunc) *SDK {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.URL.Scheme, r.URL.Host = target.Scheme, target.Host
+		return transport.RoundTrip(r)
+	})
+	t.Cleanup(func() {
+		http.DefaultTransport = transport
+		server.Close()
+	})
+
+	sdk := New("appid", "secret")
+	sdk.AccessToken, sdk.tokenExpiry = "token", time.Now().Add(time.Hour)
+	return sdk
+}
+
+func TestSendTempMessage(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cgi-bin/message/template/send" || r.URL.Query().Get("access_token") != "token" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok","msgid":200228332}`))
+	})
+	msgID, err := sdk.SendTempMessage(&TempMessage{ToUser: "openid", TemplateID: "template"})
+	if err != nil || msgID != 200228332 {
+		t.Fatalf("SendTempMessage() = %d, %v", msgID, err)
+	}
+}
+
+// cancelReader 第一次读取时取消ctx，之后阻塞到ctx结束
+type cancelReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestPostMultipart(t *testing.T) {
+	content := bytes.Repeat([]byte("wechat"), 100000)
+	tests := []struct {
+		name    string
+		reader  io.Reader
+		size    int64
+		chunked bool
+	}{
+		{"已知大小", bytes.NewReader(content), int64(len(content)), false},
+		{"未知大小", io.MultiReader(bytes.NewReader(content)), -1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				chunked := len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
+				if chunked != tt.chunked || (!tt.chunked && r.ContentLength != int64(len(body))) {
+					t.Errorf("Content-Length = %d, Transfer-Encoding = %v, body = %d bytes", r.ContentLength, r.TransferEncoding, len(body))
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				file, header, err := r.FormFile("media")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				got, _ := io.ReadAll(file)
+				if !bytes.Equal(got, content) || header.Filename != `a"b.mp4` || header.Header.Get("Content-Type") != "video/mp4" ||
+					r.FormValue("description") != `{"title":"标题"}` {
+					t.Errorf("unexpected form %s %s %d bytes", header.Filename, header.Header.Get("Content-Type"), len(got))
+				}
+				w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+			}))
+			defer server.Close()
+
+			var uploaded, total int64
+			o := newUploadOptions([]UploadOption{WithProgress(func(n, size int64) { uploaded, total = n, size })})
+			file := multipartFile{field: "media", filename: `a"b.mp4`, reader: tt.reader, size: tt.size}
+			var result Error
+			if err := postMultipart(server.URL, file, map[string]string{"description": `{"title":"标题"}`}, o, &result); err != nil {
+				t.Fatal(err)
+			}
+			if uploaded != int64(len(content)) || total != tt.size {
+				t.Fatalf("progress = %d/%d", uploaded, total)
+			}
+		})
+	}
+}
+
+// blockingReader 阻塞到release被关闭，不响应ctx的取消
+type blockingReader struct {
+	release chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	<-r.release
+	return 0, io.EOF
+}
+
+func TestPostMultipartCancelWithBlockingReader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+	reader := &blockingReader{release: make(chan struct{})}
+	defer close(reader.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		var result Error
+		done <- postMultipart(server.URL, multipartFile{field: "media", filename: "a.jpg", reader: reader, size: -1},
+			nil, newUploadOptions([]UploadOption{WithContext(ctx)}), &result)
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("postMultipart() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("postMultipart() did not return after the context was canceled")
+	}
+}
+
+func TestPostMultipartErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	readErr := errors.New("read failed")
+	reader := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(readErr))
+	var result Error
+	err := postMultipart(server.URL, multipartFile{field: "media", filename: "a.jpg", reader: reader, size: 100},
+		nil, newUploadOptions(nil), &result)
+	if !errors.Is(err, readErr) {
+		t.Fatalf("postMultipart() reader error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = postMultipart(server.URL, multipartFile{field: "media", filename: "a.jpg", reader: &cancelReader{ctx: ctx, cancel: cancel}, size: -1},
+		nil, newUploadOptions([]UploadOption{WithContext(ctx)}), &result)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("postMultipart() canceled error = %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package wechat
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GetSubscribeCategory 获取公众号类目
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#getCategory%E8%8E%B7%E5%8F%96%E5%85%AC%E4%BC%97%E5%8F%B7%E7%B1%BB%E7%9B%AE
+func (s *SDK) GetSubscribeCategory() ([]SubscribeCategory, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/wxaapi/newtmpl/getcategory?access_token=%s", s.AccessToken)
+
+	var responseJson GetSubscribeCategoryResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetSubscribeCategory, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.Data, nil
+}
+
+// GetPubTemplateTitles 获取类目下的公共模版，ids为类目id，start为分页起始位置，limit最大为30
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#getPubTemplateTitleList%E8%8E%B7%E5%8F%96%E7%B1%BB%E7%9B%AE%E4%B8%8B%E7%9A%84%E5%85%AC%E5%85%B1%E6%A8%A1%E6%9D%BF
+func (s *SDK) GetPubTemplateTitles(ids []int, start, limit int) (*GetPubTemplateTitlesResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	idList := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idList = append(idList, strconv.Itoa(id))
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/wxaapi/newtmpl/getpubtemplatetitles?access_token=%s&ids=%s&start=%d&limit=%d",
+		s.AccessToken, url.QueryEscape(strings.Join(idList, ",")), start, limit)
+
+	var responseJson GetPubTemplateTitlesResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetPubTemplateTitles, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// GetPubTemplateKeywords 获取模版中的关键词
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#getPubTemplateKeyWordsById%E8%8E%B7%E5%8F%96%E6%A8%A1%E6%9D%BF%E4%B8%AD%E7%9A%84%E5%85%B3%E9%94%AE%E8%AF%8D
+func (s *SDK) GetPubTemplateKeywords(tid string) ([]PubTemplateKeyword, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/wxaapi/newtmpl/getpubtemplatekeywords?access_token=%s&tid=%s",
+		s.AccessToken, url.QueryEscape(tid))
+
+	var responseJson GetPubTemplateKeywordsResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetPubTemplateKeywords, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.Data, nil
+}
+
+// AddSubscribeTemplate 从公共模版库中选用模版到私有模版库，返回私有模版id
+// kidList为关键词id列表，sceneDesc为服务场景描述（15个字以内）
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#addTemplate%E9%80%89%E7%94%A8%E6%A8%A1%E6%9D%BF
+func (s *SDK) AddSubscribeTemplate(tid string, kidList []int, sceneDesc string) (string, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return "", err
+	}
+	data := map[string]interface{}{
+		"tid":       tid,
+		"kidList":   kidList,
+		"sceneDesc": sceneDesc,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/wxaapi/newtmpl/addtemplate?access_token=%s", s.AccessToken)
+
+	var responseJson AddSubscribeTemplateResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return "", err
+	}
+	if responseJson.Errcode != 0 {
+		return "", ErrorHandler(ErrAddSubscribeTemplate, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.PriTmplID, nil
+}
+
+// GetSubscribeTemplates 获取私有模版列表
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#getTemplateList%E8%8E%B7%E5%8F%96%E7%A7%81%E6%9C%89%E6%A8%A1%E6%9D%BF%E5%88%97%E8%A1%A8
+func (s *SDK) GetSubscribeTemplates() ([]SubscribeTemplate, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/wxaapi/newtmpl/gettemplate?access_token=%s", s.AccessToken)
+
+	var responseJson GetSubscribeTemplatesResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetSubscribeTemplates, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.Data, nil
+}
+
+// DelSubscribeTemplate 删除私有模版
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#deleteTemplate%E5%88%A0%E9%99%A4%E6%A8%A1%E6%9D%BF
+func (s *SDK) DelSubscribeTemplate(priTmplID string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"priTmplId": priTmplID,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/wxaapi/newtmpl/deltemplate?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDelSubscribeTemplate, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// NewSubscribeMessage 实例化订阅通知
+func (s *SDK) NewSubscribeMessage(touser, templateID, page string, msgData map[string]string) *SubscribeMessage {
+	var data = make(map[string]SubscribeMessageData)
+	for key, value := range msgData {
+		data[key] = SubscribeMessageData{value}
+	}
+	return &SubscribeMessage{
+		ToUser:     touser,
+		TemplateID: templateID,
+		Page:       page,
+		Data:       data,
+	}
+}
+
+// SendSubscribeMessage 发送订阅通知
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Subscription_Messages/api.html#send%E5%8F%91%E9%80%81%E8%AE%A2%E9%98%85%E9%80%9A%E7%9F%A5
+func (s *SDK) SendSubscribeMessage(message *SubscribeMessage) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/subscribe/bizsend?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, message, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrSendSubscribeMessage, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// SubscribeStatus 用户对订阅通知模版的订阅状态
+type SubscribeStatus string
+
+const (
+	SubscribeAccept SubscribeStatus = "accept" // 同意接收
+	SubscribeReject SubscribeStatus = "reject" // 拒绝接收
+)
+
+// Subscription 用户对某个模版的订阅记录
+type Subscription struct {
+	OpenID     string          `json:"openid"`      // 用户openid
+	TemplateID string          `json:"template_id"` // 模版ID
+	Status     SubscribeStatus `json:"status"`      // 订阅状态
+	UpdatedAt  time.Time       `json:"updated_at"`  // 最后一次变更时间
+}
+
+// SubscriptionStore 订阅记录存储，可自行实现以持久化到数据库等
+type SubscriptionStore interface {
+	// Save 保存或覆盖订阅记录
+	Save(subscription Subscription) error
+	// Get 获取订阅记录，第二个返回值表示记录是否存在
+	Get(openID, templateID string) (Subscription, bool, error)
+	// List 获取用户的全部订阅记录
+	List(openID string) ([]Subscription, error)
+}
+
+// MemorySubscriptionStore 基于内存的订阅记录存储
+type MemorySubscriptionStore struct {
+	mutex   sync.RWMutex
+	records map[string]map[string]Subscription // openid -> template_id -> 记录
+}
+
+// NewMemorySubscriptionStore 实例化内存订阅记录存储
+func NewMemorySubscriptionStore() *MemorySubscriptionStore {
+	return &MemorySubscriptionStore{records: make(map[string]map[string]Subscription)}
+}
+
+func (m *MemorySubscriptionStore) Save(subscription Subscription) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	templates, ok := m.records[subscription.OpenID]
+	if !ok {
+		templates = make(map[string]Subscription)
+		m.records[subscription.OpenID] = templates
+	}
+	templates[subscription.TemplateID] = subscription
+	return nil
+}
+
+func (m *MemorySubscriptionStore) Get(openID, templateID string) (Subscription, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	subscription, ok := m.records[openID][templateID]
+	return subscription, ok, nil
+}
+
+func (m *MemorySubscriptionStore) List(openID string) ([]Subscription, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	list := make([]Subscription, 0, len(m.records[openID]))
+	for _, subscription := range m.records[openID] {
+		list = append(list, subscription)
+	}
+	return list, nil
+}
+
+// SubscribeLedger 订阅通知台账，根据订阅通知相关事件记录用户同意接收了哪些模版
+type SubscribeLedger struct {
+	OnError func(err error) // 处理事件时保存记录失败的回调，为nil时忽略错误
+
+	store SubscriptionStore
+}
+
+// NewSubscribeLedger 为SDK开启订阅通知台账，store为nil时使用内存存储
+// 每个SDK只会开启一次，重复调用时返回已有的台账并忽略store
+func NewSubscribeLedger(sdk *SDK, store SubscriptionStore) *SubscribeLedger {
+	sdk.ledgerOnce.Do(func() {
+		if store == nil {
+			store = NewMemorySubscriptionStore()
+		}
+		l := &SubscribeLedger{store: store}
+		sdk.ledger = l
+		sdk.addHook(func(msg *Message) {
+			if msg.Type == EventMessage {
+				l.handleError(l.HandleEvent(msg))
+			}
+		})
+	})
+	return sdk.ledger
+}
+
+func (l *SubscribeLedger) handleError(err error) {
+	if err != nil && l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+// HandleEvent 处理订阅通知相关事件，SDK收到事件时会自动调用
+func (l *SubscribeLedger) HandleEvent(msg *Message) error {
+	for _, event := range msg.SubscribeMsgEvents {
+		var status SubscribeStatus
+		switch msg.Event {
+		case EventSubscribeMsgPopup, EventSubscribeMsgChange:
+			status = SubscribeStatus(event.SubscribeStatusString)
+		case EventSubscribeMsgSent:
+			// 43101 用户拒绝接受消息，说明用户已取消订阅
+			if event.ErrorCode != 43101 {
+				continue
+			}
+			status = SubscribeReject
+		default:
+			continue
+		}
+		if status != SubscribeAccept && status != SubscribeReject {
+			continue
+		}
+		if err := l.Record(msg.FromUserName, event.TemplateID, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record 手动记录用户的订阅状态
+func (l *SubscribeLedger) Record(openID, templateID string, status SubscribeStatus) error {
+	return l.store.Save(Subscription{
+		OpenID:     openID,
+		TemplateID: templateID,
+		Status:     status,
+		UpdatedAt:  time.Now(),
+	})
+}
+
+// Accepted 判断用户是否同意接收某个模版的订阅通知
+func (l *SubscribeLedger) Accepted(openID, templateID string) (bool, error) {
+	subscription, ok, err := l.store.Get(openID, templateID)
+	if err != nil {
+		return false, err
+	}
+	return ok && subscription.Status == SubscribeAccept, nil
+}
+
+// Subscriptions 获取用户的全部订阅记录
+func (l *SubscribeLedger) Subscriptions(openID string) ([]Subscription, error) {
+	return l.store.List(openID)
+}
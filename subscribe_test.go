@@ -0,0 +1,254 @@
+package wechat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSubscribeAPIs(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		var body map[string]interface{}
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+		}
+		switch r.URL.Path {
+		case "/wxaapi/newtmpl/getcategory":
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","data":[{"id":616,"name":"公交"},{"id":627,"name":"乘车码"}]}`))
+		case "/wxaapi/newtmpl/getpubtemplatetitles":
+			if query.Get("ids") != "616,627" || query.Get("start") != "0" || query.Get("limit") != "30" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","count":55,"data":[{"tid":99,"title":"付款成功通知","type":2,"categoryId":"616"}]}`))
+		case "/wxaapi/newtmpl/getpubtemplatekeywords":
+			if query.Get("tid") != "99" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","count":1,"data":[{"kid":1,"name":"物品名称","example":"名称","rule":"thing"}]}`))
+		case "/wxaapi/newtmpl/addtemplate":
+			if body["tid"] != "99" || !reflect.DeepEqual(body["kidList"], []interface{}{1.0, 2.0}) || body["sceneDesc"] != "支付成功" {
+				t.Errorf("unexpected body %v", body)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","priTmplId":"9Aw5ZV1j9xdWTFEkqCpZ7mIBbSC34khK55OtzUPl0rU"}`))
+		case "/wxaapi/newtmpl/gettemplate":
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","data":[{"priTmplId":"9Aw5ZV1j9xdWTFEkqCpZ7mIBbSC34khK55OtzUPl0rU","title":"付款成功通知","content":"物品名称:{{thing1.DATA}}\n","example":"物品名称:名称\n","type":2}]}`))
+		case "/wxaapi/newtmpl/deltemplate":
+			if body["priTmplId"] != "missing" {
+				t.Errorf("unexpected body %v", body)
+			}
+			w.Write([]byte(`{"errcode":200014,"errmsg":"template id is invalid"}`))
+		case "/cgi-bin/message/subscribe/bizsend":
+			want := map[string]interface{}{
+				"touser":      "openid",
+				"template_id": "template",
+				"page":        "https://example.com",
+				"data":        map[string]interface{}{"thing1": map[string]interface{}{"value": "名称"}},
+			}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("unexpected body %v", body)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	})
+
+	categories, err := sdk.GetSubscribeCategory()
+	if err != nil || len(categories) != 2 || categories[1] != (SubscribeCategory{ID: 627, Name: "乘车码"}) {
+		t.Fatalf("GetSubscribeCategory() = %v, %v", categories, err)
+	}
+	titles, err := sdk.GetPubTemplateTitles([]int{616, 627}, 0, 30)
+	if err != nil || titles.Count != 55 || titles.Data[0].Tid != 99 || titles.Data[0].CategoryID != "616" {
+		t.Fatalf("GetPubTemplateTitles() = %+v, %v", titles, err)
+	}
+	keywords, err := sdk.GetPubTemplateKeywords("99")
+	if err != nil || len(keywords) != 1 || keywords[0].Kid != 1 || keywords[0].Rule != "thing" {
+		t.Fatalf("GetPubTemplateKeywords() = %v, %v", keywords, err)
+	}
+	priTmplID, err := sdk.AddSubscribeTemplate("99", []int{1, 2}, "支付成功")
+	if err != nil || priTmplID != "9Aw5ZV1j9xdWTFEkqCpZ7mIBbSC34khK55OtzUPl0rU" {
+		t.Fatalf("AddSubscribeTemplate() = %q, %v", priTmplID, err)
+	}
+	templates, err := sdk.GetSubscribeTemplates()
+	if err != nil || len(templates) != 1 || templates[0].PriTmplID != priTmplID || templates[0].Type != 2 {
+		t.Fatalf("GetSubscribeTemplates() = %v, %v", templates, err)
+	}
+	var apiErr *APIError
+	if err = sdk.DelSubscribeTemplate("missing"); !errors.As(err, &apiErr) || apiErr.Errcode != 200014 || apiErr.Action != ErrDelSubscribeTemplate {
+		t.Fatalf("DelSubscribeTemplate() error = %v", err)
+	}
+	message := sdk.NewSubscribeMessage("openid", "template", "https://example.com", map[string]string{"thing1": "名称"})
+	if err = sdk.SendSubscribeMessage(message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 以下事件示例来自官方文档
+const (
+	subscribeMsgPopupEventXML = `<xml>
+<ToUserName><![CDATA[gh_123456789abc]]></ToUserName>
+<FromUserName><![CDATA[otFpruAK8D-E6EfStSYonYSBZ8_4]]></FromUserName>
+<CreateTime>1610969440</CreateTime>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[subscribe_msg_popup_event]]></Event>
+<SubscribeMsgPopupEvent>
+<List>
+<TemplateId><![CDATA[VRR0UEO9VJOLs0MHlU0OilqX6MVFDwH3_3gz3Oc0NIc]]></TemplateId>
+<SubscribeStatusString><![CDATA[accept]]></SubscribeStatusString>
+<PopupScene>2</PopupScene>
+</List>
+<List>
+<TemplateId><![CDATA[9nLIlbOQZC5Y89AZteFEux3WCXRRRG5Wfzkpssu4bLI]]></TemplateId>
+<SubscribeStatusString><![CDATA[reject]]></SubscribeStatusString>
+<PopupScene>2</PopupScene>
+</List>
+</SubscribeMsgPopupEvent>
+</xml>`
+	subscribeMsgChangeEventXML = `<xml>
+<ToUserName><![CDATA[gh_123456789abc]]></ToUserName>
+<FromUserName><![CDATA[otFpruAK8D-E6EfStSYonYSBZ8_4]]></FromUserName>
+<CreateTime>1610969440</CreateTime>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[subscribe_msg_change_event]]></Event>
+<SubscribeMsgChangeEvent>
+<List>
+<TemplateId><![CDATA[VRR0UEO9VJOLs0MHlU0OilqX6MVFDwH3_3gz3Oc0NIc]]></TemplateId>
+<SubscribeStatusString><![CDATA[reject]]></SubscribeStatusString>
+</List>
+</SubscribeMsgChangeEvent>
+</xml>`
+	subscribeMsgSentEventXML = `<xml>
+<ToUserName><![CDATA[gh_123456789abc]]></ToUserName>
+<FromUserName><![CDATA[otFpruAK8D-E6EfStSYonYSBZ8_4]]></FromUserName>
+<CreateTime>1610969468</CreateTime>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[subscribe_msg_sent_event]]></Event>
+<SubscribeMsgSentEvent>
+<List>
+<TemplateId><![CDATA[VRR0UEO9VJOLs0MHlU0OilqX6MVFDwH3_3gz3Oc0NIc]]></TemplateId>
+<MsgID>1700827132819554304</MsgID>
+<ErrorCode>0</ErrorCode>
+<ErrorStatus><![CDATA[success]]></ErrorStatus>
+</List>
+</SubscribeMsgSentEvent>
+</xml>`
+)
+
+const (
+	subscribeOpenID    = "otFpruAK8D-E6EfStSYonYSBZ8_4"
+	subscribeTemplate1 = "VRR0UEO9VJOLs0MHlU0OilqX6MVFDwH3_3gz3Oc0NIc"
+	subscribeTemplate2 = "9nLIlbOQZC5Y89AZteFEux3WCXRRRG5Wfzkpssu4bLI"
+)
+
+func TestHandleWeChatMessageSubscribeEvents(t *testing.T) {
+	tests := []struct {
+		xml   string
+		event string
+		want  []SubscribeMsgEvent
+	}{
+		{subscribeMsgPopupEventXML, EventSubscribeMsgPopup, []SubscribeMsgEvent{
+			{TemplateID: subscribeTemplate1, SubscribeStatusString: "accept", PopupScene: "2"},
+			{TemplateID: subscribeTemplate2, SubscribeStatusString: "reject", PopupScene: "2"},
+		}},
+		{subscribeMsgChangeEventXML, EventSubscribeMsgChange, []SubscribeMsgEvent{
+			{TemplateID: subscribeTemplate1, SubscribeStatusString: "reject"},
+		}},
+		{subscribeMsgSentEventXML, EventSubscribeMsgSent, []SubscribeMsgEvent{
+			{TemplateID: subscribeTemplate1, MsgID: "1700827132819554304", ErrorStatus: "success"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			sdk := New("", "")
+			var got *Message
+			sdk.addHook(func(msg *Message) { got = msg })
+			sdk.HandleWeChatMessage([]byte(tt.xml), httptest.NewRecorder())
+			if got == nil || got.Type != EventMessage || got.Event != tt.event || got.FromUserName != subscribeOpenID {
+				t.Fatalf("message = %+v", got)
+			}
+			if !reflect.DeepEqual(got.SubscribeMsgEvents, tt.want) {
+				t.Fatalf("SubscribeMsgEvents = %+v, want %+v", got.SubscribeMsgEvents, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeLedger(t *testing.T) {
+	sdk := New("", "")
+	ledger := NewSubscribeLedger(sdk, nil)
+	// 重复调用返回同一个台账，事件不会被重复处理
+	if NewSubscribeLedger(sdk, nil) != ledger {
+		t.Fatal("NewSubscribeLedger returned a new ledger")
+	}
+	refused := &Message{Type: EventMessage, Event: EventSubscribeMsgSent, FromUserName: subscribeOpenID,
+		SubscribeMsgEvents: []SubscribeMsgEvent{{TemplateID: subscribeTemplate2, ErrorCode: 43101, ErrorStatus: "failed:user refuse to accept the msg"}}}
+
+	steps := []struct {
+		name      string
+		handle    func()
+		accepted1 bool
+		accepted2 bool
+		records   int
+	}{
+		{"弹窗中同意和拒绝", func() {
+			sdk.HandleWeChatMessage([]byte(subscribeMsgPopupEventXML), httptest.NewRecorder())
+		}, true, false, 2},
+		{"发送成功不改变状态", func() {
+			sdk.HandleWeChatMessage([]byte(subscribeMsgSentEventXML), httptest.NewRecorder())
+		}, true, false, 2},
+		{"管理订阅时拒绝", func() {
+			sdk.HandleWeChatMessage([]byte(subscribeMsgChangeEventXML), httptest.NewRecorder())
+		}, false, false, 2},
+		{"手动记录同意", func() {
+			if err := ledger.Record(subscribeOpenID, subscribeTemplate2, SubscribeAccept); err != nil {
+				t.Fatal(err)
+			}
+		}, false, true, 2},
+		{"用户拒绝接收", func() { sdk.runHooks(refused) }, false, false, 2},
+	}
+	for _, step := range steps {
+		step.handle()
+		accepted1, err := ledger.Accepted(subscribeOpenID, subscribeTemplate1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted2, err := ledger.Accepted(subscribeOpenID, subscribeTemplate2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subscriptions, err := ledger.Subscriptions(subscribeOpenID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accepted1 != step.accepted1 || accepted2 != step.accepted2 || len(subscriptions) != step.records {
+			t.Fatalf("%s: accepted = %v, %v, records = %d", step.name, accepted1, accepted2, len(subscriptions))
+		}
+	}
+}
+
+// failingSubscriptionStore 保存时总是失败的存储
+type failingSubscriptionStore struct {
+	*MemorySubscriptionStore
+}
+
+func (failingSubscriptionStore) Save(subscription Subscription) error {
+	return errors.New("store unavailable")
+}
+
+func TestSubscribeLedgerOnError(t *testing.T) {
+	sdk := New("", "")
+	ledger := NewSubscribeLedger(sdk, failingSubscriptionStore{NewMemorySubscriptionStore()})
+	var errs []error
+	ledger.OnError = func(err error) { errs = append(errs, err) }
+
+	sdk.HandleWeChatMessage([]byte(subscribeMsgPopupEventXML), httptest.NewRecorder())
+	if len(errs) != 1 || errs[0].Error() != "store unavailable" {
+		t.Fatalf("OnError received %v", errs)
+	}
+}
@@ -0,0 +1,215 @@
+package wechat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoOneTimeGrant 用户没有可用的一次性订阅授权
+	ErrNoOneTimeGrant = errors.New("用户没有可用的一次性订阅授权")
+	// ErrOneTimeSubscribeCanceled 用户取消了一次性订阅授权
+	ErrOneTimeSubscribeCanceled = errors.New("用户取消了一次性订阅授权")
+	// ErrInvalidOneTimeRedirect 一次性订阅授权回调参数不合法
+	ErrInvalidOneTimeRedirect = errors.New("一次性订阅授权回调参数不合法")
+)
+
+// BuildSubscribeMsgURL 构造一次性订阅消息授权页面地址，需在微信客户端中打开
+// scene 为重定向后会带上的场景值，范围0-10000；reserved 用于保持请求和回调的状态，最长128字节，可用于防止csrf攻击
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/One-time_subscription_info.html
+func (s *SDK) BuildSubscribeMsgURL(scene int, templateID, redirectURL, reserved string) (string, error) {
+	if scene < 0 || scene > 10000 {
+		return "", fmt.Errorf("scene取值范围为0-10000，当前为%d", scene)
+	}
+	if templateID == "" || redirectURL == "" {
+		return "", errors.New("template_id和redirect_url不能为空")
+	}
+	if len(reserved) > 128 {
+		return "", fmt.Errorf("reserved最长128字节，当前为%d字节", len(reserved))
+	}
+	return fmt.Sprintf("https://mp.weixin.qq.com/mp/subscribemsg?action=get_confirm&appid=%s&scene=%d&template_id=%s&redirect_url=%s&reserved=%s#wechat_redirect",
+		url.QueryEscape(s.AppID), scene, url.QueryEscape(templateID), url.QueryEscape(redirectURL), url.QueryEscape(reserved)), nil
+}
+
+// OneTimeGrant 用户的一次性订阅授权，每次授权只能下发一条消息
+type OneTimeGrant struct {
+	OpenID     string    `json:"openid"`      // 用户openid
+	TemplateID string    `json:"template_id"` // 订阅消息模版ID
+	Scene      int       `json:"scene"`       // 订阅场景值
+	Reserved   string    `json:"reserved"`    // 授权时带上的reserved
+	GrantedAt  time.Time `json:"granted_at"`  // 授权时间
+}
+
+// OneTimeGrantStore 一次性订阅授权存储，可自行实现以持久化到数据库等
+// Consume 需保证同一条授权只会被取出一次
+type OneTimeGrantStore interface {
+	// Add 记录一次授权
+	Add(grant OneTimeGrant) error
+	// Consume 取出并删除一条授权，没有可用授权时返回 ErrNoOneTimeGrant
+	Consume(openID, templateID string, scene int) (OneTimeGrant, error)
+}
+
+// MemoryOneTimeGrantStore 基于内存的一次性订阅授权存储
+type MemoryOneTimeGrantStore struct {
+	mutex  sync.Mutex
+	grants map[string][]OneTimeGrant
+}
+
+// NewMemoryOneTimeGrantStore 实例化内存一次性订阅授权存储
+func NewMemoryOneTimeGrantStore() *MemoryOneTimeGrantStore {
+	return &MemoryOneTimeGrantStore{grants: make(map[string][]OneTimeGrant)}
+}
+
+func oneTimeGrantKey(openID, templateID string, scene int) string {
+	return fmt.Sprintf("%s|%s|%d", openID, templateID, scene)
+}
+
+func (m *MemoryOneTimeGrantStore) Add(grant OneTimeGrant) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := oneTimeGrantKey(grant.OpenID, grant.TemplateID, grant.Scene)
+	m.grants[key] = append(m.grants[key], grant)
+	return nil
+}
+
+func (m *MemoryOneTimeGrantStore) Consume(openID, templateID string, scene int) (OneTimeGrant, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := oneTimeGrantKey(openID, templateID, scene)
+	grants := m.grants[key]
+	if len(grants) == 0 {
+		return OneTimeGrant{}, ErrNoOneTimeGrant
+	}
+	grant := grants[0]
+	if len(grants) == 1 {
+		delete(m.grants, key)
+	} else {
+		m.grants[key] = grants[1:]
+	}
+	return grant, nil
+}
+
+// OneTimeSubscribeHandler 一次性订阅授权回调处理器，挂载在 BuildSubscribeMsgURL 的 redirect_url 上
+// 用户确认授权后，微信会带上 openid、template_id、action、scene、reserved 参数重定向到该地址
+// 回调参数可以被任意伪造，必须设置 VerifyReserved 校验reserved是否为 BuildSubscribeMsgURL 时签发的值
+type OneTimeSubscribeHandler struct {
+	Store      OneTimeGrantStore // 授权存储，不能为空
+	TemplateID string            // 期望的模版ID，为空时不校验
+	// VerifyReserved 校验reserved，如校验签发给该用户的csrf state，不能为空
+	VerifyReserved func(reserved string) bool
+	// OnResult 授权处理完成后的回调，err为nil表示授权已记录，为nil时输出简单的文本结果
+	OnResult func(w http.ResponseWriter, r *http.Request, grant *OneTimeGrant, err error)
+}
+
+func (h *OneTimeSubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 未配置存储或reserved校验时拒绝所有回调，避免记录伪造的授权
+	if h.Store == nil || h.VerifyReserved == nil {
+		http.Error(w, "OneTimeSubscribeHandler未设置Store或VerifyReserved", http.StatusInternalServerError)
+		return
+	}
+	grant, err := h.parse(r)
+	if err == nil {
+		err = h.Store.Add(*grant)
+	}
+
+	if h.OnResult != nil {
+		h.OnResult(w, r, grant, err)
+		return
+	}
+	switch {
+	case err == nil:
+		fmt.Fprint(w, "订阅成功")
+	case errors.Is(err, ErrOneTimeSubscribeCanceled):
+		fmt.Fprint(w, "已取消订阅")
+	case errors.Is(err, ErrInvalidOneTimeRedirect):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parse 解析并校验回调参数
+func (h *OneTimeSubscribeHandler) parse(r *http.Request) (*OneTimeGrant, error) {
+	query := r.URL.Query()
+	openID := query.Get("openid")
+	templateID := query.Get("template_id")
+	reserved := query.Get("reserved")
+	if openID == "" || templateID == "" {
+		return nil, fmt.Errorf("%w：缺少openid或template_id", ErrInvalidOneTimeRedirect)
+	}
+	if h.TemplateID != "" && templateID != h.TemplateID {
+		return nil, fmt.Errorf("%w：template_id不匹配", ErrInvalidOneTimeRedirect)
+	}
+	scene, err := strconv.Atoi(query.Get("scene"))
+	if err != nil || scene < 0 || scene > 10000 {
+		return nil, fmt.Errorf("%w：scene不合法", ErrInvalidOneTimeRedirect)
+	}
+	if !h.VerifyReserved(reserved) {
+		return nil, fmt.Errorf("%w：reserved校验失败", ErrInvalidOneTimeRedirect)
+	}
+	if query.Get("action") != "confirm" {
+		return nil, ErrOneTimeSubscribeCanceled
+	}
+	return &OneTimeGrant{
+		OpenID:     openID,
+		TemplateID: templateID,
+		Scene:      scene,
+		Reserved:   reserved,
+		GrantedAt:  time.Now(),
+	}, nil
+}
+
+// errcodeUserRefused 用户拒绝接收消息的错误码，此时授权视为已使用
+const errcodeUserRefused = 43101
+
+// SendOneTimeSubscribeMessage 发送一次性订阅消息，发送前从store中消耗一条用户授权
+// 只有确定消息未发出时才归还授权：请求发出前失败，或接口明确拒绝（43101用户拒绝接收除外）
+// 网络错误或响应无法解析时无法确定微信是否已处理，授权保持已消耗，避免重试时重复使用同一条授权
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/Message_Management/One-time_subscription_info.html
+func (s *SDK) SendOneTimeSubscribeMessage(store OneTimeGrantStore, message *OneTimeSubscribeMessage) error {
+	grant, err := store.Consume(message.ToUser, message.TemplateID, message.Scene)
+	if err != nil {
+		return err
+	}
+
+	requested, err := s.sendOneTimeSubscribeMessage(message)
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	rejected := errors.As(err, &apiErr) && apiErr.Errcode != errcodeUserRefused
+	if requested && !rejected {
+		return err
+	}
+	if addErr := store.Add(grant); addErr != nil {
+		return fmt.Errorf("%v；授权归还失败：%v", err, addErr)
+	}
+	return err
+}
+
+// sendOneTimeSubscribeMessage 发送一次性订阅消息，requested表示请求是否已发出
+func (s *SDK) sendOneTimeSubscribeMessage(message *OneTimeSubscribeMessage) (requested bool, err error) {
+	if err = s.checkAccessToken(); err != nil {
+		return false, err
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return false, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/message/template/subscribe?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err = postJSON(url, json.RawMessage(data), &responseJson); err != nil {
+		return true, err
+	}
+	if responseJson.Errcode != 0 {
+		return true, ErrorHandler(ErrSendOneTimeSubscribe, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package wechat
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOneTimeSubscribeHandler(t *testing.T) {
+	store := NewMemoryOneTimeGrantStore()
+	handler := &OneTimeSubscribeHandler{
+		Store:      store,
+		TemplateID: "template",
+		VerifyReserved: func(reserved string) bool {
+			return reserved == "state"
+		},
+	}
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"确认授权", "openid=o1&template_id=template&action=confirm&scene=1&reserved=state", http.StatusOK},
+		{"取消授权", "openid=o1&template_id=template&action=cancel&scene=1&reserved=state", http.StatusOK},
+		{"伪造reserved", "openid=o2&template_id=template&action=confirm&scene=1&reserved=forged", http.StatusBadRequest},
+		{"模版不匹配", "openid=o2&template_id=other&action=confirm&scene=1&reserved=state", http.StatusBadRequest},
+		{"缺少openid", "template_id=template&action=confirm&scene=1&reserved=state", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/subscribe?"+tt.query, nil))
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+		})
+	}
+
+	if _, err := store.Consume("o1", "template", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Consume("o1", "template", 1); !errors.Is(err, ErrNoOneTimeGrant) {
+		t.Fatalf("second Consume() error = %v", err)
+	}
+	if _, err := store.Consume("o2", "template", 1); !errors.Is(err, ErrNoOneTimeGrant) {
+		t.Fatalf("forged grant was stored: %v", err)
+	}
+}
+
+func TestOneTimeSubscribeHandlerRequiresVerification(t *testing.T) {
+	for _, handler := range []*OneTimeSubscribeHandler{
+		{Store: NewMemoryOneTimeGrantStore()},
+		{VerifyReserved: func(string) bool { return true }},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/subscribe?openid=o&template_id=t&action=confirm&scene=1", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", w.Code)
+		}
+	}
+}
+
+func TestSendOneTimeSubscribeMessageGrant(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantErr   bool
+		wantGrant bool // 发送后授权是否被归还
+		noToken   bool // 没有缓存的access_token，发送前需要先获取
+	}{
+		{"发送成功", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}, false, false, false},
+		{"接口拒绝", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errcode":40037,"errmsg":"invalid template_id"}`))
+		}, true, true, false},
+		{"用户拒绝接收", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errcode":43101,"errmsg":"user refuse to accept the msg"}`))
+		}, true, false, false},
+		{"响应无法解析", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>502 Bad Gateway</html>"))
+		}, true, false, false},
+		{"连接中断", func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}, true, false, false},
+		{"获取access_token失败", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/cgi-bin/token" {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			w.Write([]byte(`{"errcode":40013,"errmsg":"invalid appid"}`))
+		}, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk := newTestSDK(t, tt.handler)
+			if tt.noToken {
+				sdk.AccessToken = ""
+			}
+			store := NewMemoryOneTimeGrantStore()
+			store.Add(OneTimeGrant{OpenID: "o", TemplateID: "t", Scene: 1})
+
+			err := sdk.SendOneTimeSubscribeMessage(store, &OneTimeSubscribeMessage{ToUser: "o", TemplateID: "t", Scene: 1})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendOneTimeSubscribeMessage() error = %v", err)
+			}
+			_, err = store.Consume("o", "t", 1)
+			if (err == nil) != tt.wantGrant {
+				t.Fatalf("grant returned = %v, want %v", err == nil, tt.wantGrant)
+			}
+		})
+	}
+}
+
+func TestSendOneTimeSubscribeMessageWithoutGrant(t *testing.T) {
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	})
+	err := sdk.SendOneTimeSubscribeMessage(NewMemoryOneTimeGrantStore(), &OneTimeSubscribeMessage{ToUser: "o", TemplateID: "t", Scene: 1})
+	if !errors.Is(err, ErrNoOneTimeGrant) {
+		t.Fatalf("SendOneTimeSubscribeMessage() error = %v", err)
+	}
+}
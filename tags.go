@@ -0,0 +1,193 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+)
+
+// batchTaggingLimit 批量打标签、取消标签时单次请求的openid数量上限
+const batchTaggingLimit = 50
+
+// chunkOpenIDs 按单次请求数量上限切分openid列表
+func chunkOpenIDs(openIDs []string, size int) [][]string {
+	var chunks [][]string
+	for start := 0; start < len(openIDs); start += size {
+		end := start + size
+		if end > len(openIDs) {
+			end = len(openIDs)
+		}
+		chunks = append(chunks, openIDs[start:end])
+	}
+	return chunks
+}
+
+// CreateTag 创建标签，一个公众号最多可以创建100个标签
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) CreateTag(name string) (*Tag, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"tag": map[string]interface{}{"name": name},
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/create?access_token=%s", s.AccessToken)
+
+	var responseJson TagResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrCreateTag, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson.Tag, nil
+}
+
+// GetTags 获取公众号已创建的标签
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) GetTags() ([]Tag, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/get?access_token=%s", s.AccessToken)
+
+	var responseJson GetTagsResponse
+	if err := getJSON(url, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetTags, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.Tags, nil
+}
+
+// UpdateTag 编辑标签
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) UpdateTag(tagID int, name string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"tag": map[string]interface{}{"id": tagID, "name": name},
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/update?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrUpdateTag, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// DeleteTag 删除标签，粉丝数超过10w的标签无法直接删除，需先取消部分粉丝的标签
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) DeleteTag(tagID int) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"tag": map[string]interface{}{"id": tagID},
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/delete?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrDeleteTag, responseJson.Errmsg, responseJson.Errcode)
+	}
+	// 删除标签会影响该标签下的全部用户
+	if cache := s.cache(); cache != nil {
+		cache.Purge()
+	}
+	return nil
+}
+
+// BatchTagging 批量为用户打标签，超过50个openid时自动分批请求
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) BatchTagging(tagID int, openIDs []string) error {
+	return s.batchTagging("batchtagging", ErrBatchTagging, tagID, openIDs)
+}
+
+// BatchUntagging 批量为用户取消标签，超过50个openid时自动分批请求
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) BatchUntagging(tagID int, openIDs []string) error {
+	return s.batchTagging("batchuntagging", ErrBatchUntagging, tagID, openIDs)
+}
+
+func (s *SDK) batchTagging(action, errAction string, tagID int, openIDs []string) error {
+	for _, chunk := range chunkOpenIDs(openIDs, batchTaggingLimit) {
+		if err := s.checkAccessToken(); err != nil {
+			return err
+		}
+		data := map[string]interface{}{
+			"openid_list": chunk,
+			"tagid":       tagID,
+		}
+		url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/members/%s?access_token=%s", action, s.AccessToken)
+
+		var responseJson Error
+		if err := postJSON(url, data, &responseJson); err != nil {
+			return err
+		}
+		if responseJson.Errcode != 0 {
+			return ErrorHandler(errAction, responseJson.Errmsg, responseJson.Errcode)
+		}
+		s.invalidateUsers(chunk...)
+	}
+	return nil
+}
+
+// GetUserTagIDs 获取用户身上的标签列表
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) GetUserTagIDs(openID string) ([]int, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/getidlist?access_token=%s", s.AccessToken)
+
+	var responseJson GetUserTagIDsResponse
+	if err := postJSON(url, map[string]interface{}{"openid": openID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetUserTagIDs, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return responseJson.TagIDList, nil
+}
+
+// GetTagUsers 获取标签下粉丝列表，nextOpenID为空时从头开始拉取，每次最多拉取10000个
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html
+func (s *SDK) GetTagUsers(tagID int, nextOpenID string) (*GetTagUsersResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"tagid":       tagID,
+		"next_openid": nextOpenID,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/user/tag/get?access_token=%s", s.AccessToken)
+
+	var responseJson GetTagUsersResponse
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetTagUsers, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// TagUsers 返回标签下全部粉丝的迭代器，自动处理分页，startOpenID为空时从头开始
+func (s *SDK) TagUsers(ctx context.Context, tagID int, startOpenID string) *OpenIDIterator {
+	return newOpenIDIterator(ctx, startOpenID, func(nextOpenID string) ([]string, string, error) {
+		resp, err := s.GetTagUsers(tagID, nextOpenID)
+		if err != nil {
+			return nil, "", err
+		}
+		return resp.Data.OpenID, resp.NextOpenID, nil
+	})
+}
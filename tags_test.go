@@ -0,0 +1,86 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBatchTagging(t *testing.T) {
+	var (
+		sizes  []int
+		failAt int
+	)
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OpenIDList []string `json:"openid_list"`
+			TagID      int      `json:"tagid"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if r.URL.Path != "/cgi-bin/tags/members/batchtagging" || body.TagID != 100 {
+			t.Errorf("unexpected request %s %+v", r.URL.Path, body)
+		}
+		sizes = append(sizes, len(body.OpenIDList))
+		if len(sizes) == failAt {
+			w.Write([]byte(`{"errcode":45159,"errmsg":"invalid tag"}`))
+			return
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	})
+
+	if err := sdk.BatchTagging(100, openIDList(120)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sizes, []int{50, 50, 20}) {
+		t.Fatalf("chunk sizes = %v", sizes)
+	}
+	// 出错时停止后续批次
+	sizes, failAt = nil, 2
+	if err := sdk.BatchTagging(100, openIDList(120)); err == nil {
+		t.Fatal("BatchTagging() error = nil")
+	}
+	if len(sizes) != 2 {
+		t.Fatalf("sent %d requests after error, want 3", len(sizes))
+	}
+	if err := sdk.BatchTagging(100, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTagUsers(t *testing.T) {
+	openIDs := openIDList(15000)
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			NextOpenID string `json:"next_openid"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		var resp GetTagUsersResponse
+		switch body.NextOpenID {
+		case "":
+			resp.Count, resp.NextOpenID = 10000, openIDs[9999]
+			resp.Data.OpenID = openIDs[:10000]
+		case openIDs[9999]:
+			resp.Count, resp.NextOpenID = 5000, openIDs[14999]
+			resp.Data.OpenID = openIDs[10000:]
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	it := sdk.TagUsers(context.Background(), 100, "")
+	var got []string
+	for it.Next() {
+		got = append(got, it.OpenID())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, openIDs) {
+		t.Fatalf("TagUsers() yielded %d openids, want %d", len(got), len(openIDs))
+	}
+}
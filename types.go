@@ -1,16 +1,30 @@
 package wechat
 
 import (
+	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
 
-// ErrorHandler 错误处理
+// APIError 微信接口返回的错误，可通过 errors.As 获取错误码
+type APIError struct {
+	Action  string // 失败的操作，如 模版消息发送失败
+	Errcode int    // 错误码
+	Errmsg  string // 错误信息
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s:%s,错误码：%d", e.Action, e.Errmsg, e.Errcode)
+}
+
+// ErrorHandler 错误处理，返回 *APIError
 func ErrorHandler(action, errmsg string, errcode int) error {
-	return fmt.Errorf("%s:%s,错误码：%d", action, errmsg, errcode)
+	return &APIError{Action: action, Errcode: errcode, Errmsg: errmsg}
 }
 
 var (
@@ -18,10 +32,72 @@ var (
 	ErrSendTempMessage        = "模版消息发送失败"
 	ErrSendTextMessage        = "文本消息发送失败"
 	ErrCreateMenu             = "自定义菜单创建失败"
+	ErrAddMaterial            = "永久素材新增失败"
+	ErrGetMaterial            = "永久素材获取失败"
+	ErrDelMaterial            = "永久素材删除失败"
+	ErrGetMaterialCount       = "素材总数获取失败"
+	ErrBatchGetMaterial       = "素材列表获取失败"
+	ErrAddDraft               = "草稿新建失败"
+	ErrGetDraft               = "草稿获取失败"
+	ErrUpdateDraft            = "草稿修改失败"
+	ErrDeleteDraft            = "草稿删除失败"
+	ErrGetDraftCount          = "草稿总数获取失败"
+	ErrBatchGetDraft          = "草稿列表获取失败"
+	ErrSubmitPublish          = "发布任务提交失败"
+	ErrGetPublishStatus       = "发布状态查询失败"
+	ErrDeletePublish          = "已发布文章删除失败"
+	ErrGetPublishedArticle    = "已发布文章获取失败"
+	ErrBatchGetPublished      = "已发布文章列表获取失败"
+	ErrOpenComment            = "评论开启失败"
+	ErrCloseComment           = "评论关闭失败"
+	ErrListComment            = "评论列表获取失败"
+	ErrMarkElectComment       = "评论精选失败"
+	ErrUnmarkElectComment     = "评论取消精选失败"
+	ErrDeleteComment          = "评论删除失败"
+	ErrReplyComment           = "评论回复失败"
+	ErrDeleteCommentReply     = "评论回复删除失败"
+	ErrUploadTempMedia        = "临时素材上传失败"
+	ErrUploadArticleImage     = "图文消息图片上传失败"
+	ErrDownloadMedia          = "临时素材下载失败"
+	ErrDownloadJSSDKVoice     = "高清语音素材下载失败"
+	ErrAddConditionalMenu     = "个性化菜单创建失败"
+	ErrDelConditionalMenu     = "个性化菜单删除失败"
+	ErrTryMatchMenu           = "个性化菜单匹配失败"
+	ErrGetMenu                = "自定义菜单查询失败"
+	ErrDeleteMenu             = "自定义菜单删除失败"
+	ErrGetCurrentSelfMenuInfo = "当前自定义菜单配置获取失败"
 	ErrSendMiniprogramMessage = "小程序卡片消息发送失败"
 	ErrGetUserList            = "用户列表获取失败"
 	ErrGetUserInfo            = "用户基础信息获取失败"
+	ErrBatchGetUserInfo       = "批量获取用户基础信息失败"
+	ErrUpdateUserRemark       = "用户备注名设置失败"
+	ErrGetBlacklist           = "黑名单列表获取失败"
+	ErrBatchBlacklist         = "拉黑用户失败"
+	ErrBatchUnblacklist       = "取消拉黑用户失败"
+	ErrChangeOpenID           = "openid转换失败"
 	ErrGetWebAuthAccessToken  = "网页授权access_token获取失败"
+	ErrGetSubscribeCategory   = "公众号类目获取失败"
+	ErrGetPubTemplateTitles   = "公共模版标题获取失败"
+	ErrGetPubTemplateKeywords = "公共模版关键词获取失败"
+	ErrAddSubscribeTemplate   = "订阅通知模版添加失败"
+	ErrGetSubscribeTemplates  = "订阅通知模版列表获取失败"
+	ErrDelSubscribeTemplate   = "订阅通知模版删除失败"
+	ErrSendSubscribeMessage   = "订阅通知发送失败"
+	ErrSendOneTimeSubscribe   = "一次性订阅消息发送失败"
+	ErrSendMassMessage        = "群发消息发送失败"
+	ErrPreviewMassMessage     = "群发消息预览失败"
+	ErrGetMassStatus          = "群发消息发送状态查询失败"
+	ErrDeleteMassMessage      = "群发消息删除失败"
+	ErrGetMassSpeed           = "群发速度获取失败"
+	ErrSetMassSpeed           = "群发速度设置失败"
+	ErrCreateTag              = "标签创建失败"
+	ErrGetTags                = "标签列表获取失败"
+	ErrUpdateTag              = "标签编辑失败"
+	ErrDeleteTag              = "标签删除失败"
+	ErrBatchTagging           = "批量为用户打标签失败"
+	ErrBatchUntagging         = "批量为用户取消标签失败"
+	ErrGetUserTagIDs          = "用户身上的标签列表获取失败"
+	ErrGetTagUsers            = "标签下粉丝列表获取失败"
 )
 
 type MessageType string
@@ -37,6 +113,25 @@ const (
 	EventMessage      MessageType = "event"      // 事件消息
 )
 
+// 事件类型
+const (
+	EventSubscribe             = "subscribe"                  // 关注
+	EventUnsubscribe           = "unsubscribe"                // 取消关注
+	EventTemplateSendJobFinish = "TEMPLATESENDJOBFINISH"      // 模版消息发送任务完成
+	EventSubscribeMsgPopup     = "subscribe_msg_popup_event"  // 用户操作订阅通知弹窗
+	EventSubscribeMsgChange    = "subscribe_msg_change_event" // 用户管理订阅通知
+	EventSubscribeMsgSent      = "subscribe_msg_sent_event"   // 发送订阅通知
+	EventMassSendJobFinish     = "MASSSENDJOBFINISH"          // 群发任务完成
+	EventPublishJobFinish      = "PUBLISHJOBFINISH"           // 发布任务完成
+)
+
+// 返回用户信息时使用的语言
+const (
+	LangZhCN = "zh_CN" // 简体
+	LangZhTW = "zh_TW" // 繁体
+	LangEn   = "en"    // 英语
+)
+
 type MessageHandler func(msg *Message, w http.ResponseWriter)
 
 type SDK struct {
@@ -46,6 +141,18 @@ type SDK struct {
 	AccessToken string
 	tokenExpiry time.Time  // 过期时间
 	tokenMutex  sync.Mutex // 互斥锁，确保线程安全
+
+	hooks      []func(msg *Message) // 内部事件钩子，在用户处理器之前调用
+	hooksMutex sync.RWMutex
+	tracker    *DeliveryTracker // 模版消息送达跟踪
+	ledger     *SubscribeLedger // 订阅通知台账
+	userCache  *UserCache       // 用户信息缓存
+	cacheMutex sync.RWMutex
+
+	publishes   *publishWaiters // PublishAndWait 等待中的发布任务
+	publishOnce sync.Once
+	trackerOnce sync.Once // 保证 NewDeliveryTracker 只注册一次钩子
+	ledgerOnce  sync.Once // 保证 NewSubscribeLedger 只注册一次钩子
 }
 
 // XMLMessage 微信xml消息格式
@@ -64,6 +171,61 @@ type XMLMessage struct {
 	Format       string   `xml:"Format,omitempty"`      // 语音格式，如amr，speex等
 	Recognition  string   `xml:"Recognition,omitempty"` // 语音识别结果，UTF8编码 (已废弃)
 	Event        string   `xml:"Event,omitempty"`       // 事件类型
+	MsgID        int64    `xml:"MsgID,omitempty"`       // 事件推送中的消息ID（如模版消息发送任务完成事件）
+	Status       string   `xml:"Status,omitempty"`      // 事件推送中的发送状态
+
+	SubscribeMsgPopupEvent  []SubscribeMsgEvent `xml:"SubscribeMsgPopupEvent>List"`  // 订阅通知弹窗事件
+	SubscribeMsgChangeEvent []SubscribeMsgEvent `xml:"SubscribeMsgChangeEvent>List"` // 订阅通知管理事件
+	SubscribeMsgSentEvent   []SubscribeMsgEvent `xml:"SubscribeMsgSentEvent>List"`   // 订阅通知发送结果事件
+
+	MassSendJobResult // 群发任务完成事件
+
+	PublishEventInfo *PublishEventInfo `xml:"PublishEventInfo"` // 发布任务完成事件
+}
+
+// MassSendJobResult 群发任务完成事件推送的结果
+type MassSendJobResult struct {
+	TotalCount           int                       `xml:"TotalCount"`           // 标签粉丝数，或者openid列表中的粉丝数
+	FilterCount          int                       `xml:"FilterCount"`          // 过滤后准备发送的粉丝数
+	SentCount            int                       `xml:"SentCount"`            // 发送成功的粉丝数
+	ErrorCount           int                       `xml:"ErrorCount"`           // 发送失败的粉丝数
+	CopyrightCheckResult *MassCopyrightCheckResult `xml:"CopyrightCheckResult"` // 图文原创校验结果
+	ArticleUrlResult     *MassArticleUrlResult     `xml:"ArticleUrlResult"`     // 群发文章的url
+}
+
+// MassCopyrightCheckResult 群发图文原创校验结果
+type MassCopyrightCheckResult struct {
+	Count      int `xml:"Count"` // 校验的文章数
+	ResultList []struct {
+		ArticleIdx            int    `xml:"ArticleIdx"`            // 群发文章的序号，从1开始
+		UserDeclareState      int    `xml:"UserDeclareState"`      // 用户声明文章的状态
+		AuditState            int    `xml:"AuditState"`            // 系统校验的状态
+		OriginalArticleUrl    string `xml:"OriginalArticleUrl"`    // 相似原创文的url
+		OriginalArticleType   int    `xml:"OriginalArticleType"`   // 相似原创文的类型
+		CanReprint            int    `xml:"CanReprint"`            // 是否能转载
+		NeedReplaceContent    int    `xml:"NeedReplaceContent"`    // 是否需要替换成原创文内容
+		NeedShowReprintSource int    `xml:"NeedShowReprintSource"` // 是否需要注明转载来源
+	} `xml:"ResultList>item"`
+	CheckState int `xml:"CheckState"` // 整体校验结果，1 未被判为转载，可以群发；2 被判为转载，可以群发；3 被判为转载，不能群发
+}
+
+// MassArticleUrlResult 群发文章的url
+type MassArticleUrlResult struct {
+	Count      int `xml:"Count"` // 文章数
+	ResultList []struct {
+		ArticleIdx int    `xml:"ArticleIdx"` // 群发文章的序号，从1开始
+		ArticleUrl string `xml:"ArticleUrl"` // 群发文章的url
+	} `xml:"ResultList>item"`
+}
+
+// SubscribeMsgEvent 订阅通知相关事件中的单个模版
+type SubscribeMsgEvent struct {
+	TemplateID            string `xml:"TemplateId"`            // 模版ID
+	SubscribeStatusString string `xml:"SubscribeStatusString"` // 用户点击行为，accept 同意，reject 拒绝
+	PopupScene            string `xml:"PopupScene"`            // 弹窗场景，0 在手机端服务号主页点击，1 在支付完成页，2 在文章中
+	MsgID                 string `xml:"MsgID"`                 // 消息ID（发送结果事件）
+	ErrorCode             int    `xml:"ErrorCode"`             // 推送结果状态码，0表示成功（发送结果事件）
+	ErrorStatus           string `xml:"ErrorStatus"`           // 推送结果状态码对应的含义（发送结果事件）
 }
 
 type Message struct {
@@ -73,6 +235,15 @@ type Message struct {
 	FromUserName string      // 发送方openid
 	MediaId      string      // 素材ID
 	Event        string      // 事件类型
+	MsgID        int64       // 事件对应的消息ID（模版消息发送任务完成事件）
+	Status       string      // 事件对应的发送状态，如 success、failed:user block、failed: system failed
+
+	SubscribeMsgEvents []SubscribeMsgEvent // 订阅通知相关事件中的模版列表
+	MassSendJob        *MassSendJobResult  // 群发任务完成事件的结果，Status为 send success、send fail 或 err(num)
+	PublishEvent       *PublishEventInfo   // 发布任务完成事件的结果
+
+	sdk  *SDK      // 处理该消息的SDK，用于 User() 获取用户信息
+	user *UserInfo // User() 获取到的用户信息
 }
 
 type Error struct {
@@ -90,7 +261,7 @@ type AccessTokenResponse struct {
 // SendTempMessageResponse 发送模版消息响应
 type SendTempMessageResponse struct {
 	Error
-	MsgID int `json:"msgid"` // 消息ID
+	MsgID int64 `json:"msgid"` // 消息ID
 }
 
 // TempMessage 模版消息通用格式
@@ -121,19 +292,31 @@ type GetUserListResponse struct {
 	Error
 }
 
+// UserInfo 用户基本信息
+type UserInfo struct {
+	Subscribe      int    `json:"subscribe"`               // 用户是否订阅该公众号标识，值为0时，代表此用户没有关注该公众号，拉取不到其余信息。
+	OpenID         string `json:"openid"`                  // 用户的标识，对当前公众号唯一
+	Language       string `json:"language"`                // 用户的语言，简体中文为zh_CN
+	SubscribeTime  int    `json:"subscribe_time"`          // 用户关注时间，为时间戳。如果用户曾多次关注，则取最后关注时间
+	UnionID        string `json:"unionid"`                 // 只有在用户将公众号绑定到微信开放平台账号后，才会出现该字段。
+	Remark         string `json:"remark"`                  // 公众号运营者对粉丝的备注，公众号运营者可在微信公众平台用户管理界面对粉丝添加备注
+	GroupID        int    `json:"groupid"`                 // 用户所在的分组ID（兼容旧的用户分组接口）
+	TagIDList      []int  `json:"tagid_list"`              // 用户被打上的标签ID列表
+	SubScribeScene string `json:"subscribe_scene"`         // 返回用户关注的渠道来源，ADD_SCENE_SEARCH 公众号搜索，ADD_SCENE_ACCOUNT_MIGRATION 公众号迁移，ADD_SCENE_PROFILE_CARD 名片分享，ADD_SCENE_QR_CODE 扫描二维码，ADD_SCENE_PROFILE_LINK 图文页内名称点击，ADD_SCENE_PROFILE_ITEM 图文页右上角菜单，ADD_SCENE_PAID 支付后关注，ADD_SCENE_WECHAT_ADVERTISEMENT 微信广告，ADD_SCENE_REPRINT 他人转载 ,ADD_SCENE_LIVESTREAM 视频号直播，ADD_SCENE_CHANNELS 视频号 , ADD_SCENE_OTHERS 其他
+	QRScene        int    `json:"qr_scene"`                // 二维码扫码场景（开发者自定义）
+	QRSceneStr     string `json:"qr_scene_str"`            // 二维码扫码场景描述（开发者自定义）
+	MigratedFrom   string `json:"migrated_from,omitempty"` // 本地字段，openid迁移前的旧openid，由 OpenIDMigrator 写入，接口不返回
+}
+
 // GetUserInfoResponse 获取用户基本信息响应
 type GetUserInfoResponse struct {
-	Subscribe      int    `json:"subscribe"`       // 用户是否订阅该公众号标识，值为0时，代表此用户没有关注该公众号，拉取不到其余信息。
-	OpenID         string `json:"openid"`          // 用户的标识，对当前公众号唯一
-	Language       string `json:"language"`        // 用户的语言，简体中文为zh_CN
-	SubscribeTime  int    `json:"subscribe_time"`  // 用户关注时间，为时间戳。如果用户曾多次关注，则取最后关注时间
-	UnionID        string `json:"unionid"`         // 只有在用户将公众号绑定到微信开放平台账号后，才会出现该字段。
-	Remark         string `json:"remark"`          // 公众号运营者对粉丝的备注，公众号运营者可在微信公众平台用户管理界面对粉丝添加备注
-	GroupID        int    `json:"groupid"`         // 用户所在的分组ID（兼容旧的用户分组接口）
-	TagIDList      []int  `json:"tagid_list"`      // 用户被打上的标签ID列表
-	SubScribeScene string `json:"subscribe_scene"` // 返回用户关注的渠道来源，ADD_SCENE_SEARCH 公众号搜索，ADD_SCENE_ACCOUNT_MIGRATION 公众号迁移，ADD_SCENE_PROFILE_CARD 名片分享，ADD_SCENE_QR_CODE 扫描二维码，ADD_SCENE_PROFILE_LINK 图文页内名称点击，ADD_SCENE_PROFILE_ITEM 图文页右上角菜单，ADD_SCENE_PAID 支付后关注，ADD_SCENE_WECHAT_ADVERTISEMENT 微信广告，ADD_SCENE_REPRINT 他人转载 ,ADD_SCENE_LIVESTREAM 视频号直播，ADD_SCENE_CHANNELS 视频号 , ADD_SCENE_OTHERS 其他
-	QRScene        int    `json:"qr_scene"`        // 二维码扫码场景（开发者自定义）
-	QRSceneStr     string `json:"qr_scene_str"`    // 二维码扫码场景描述（开发者自定义）
+	UserInfo
+	Error
+}
+
+// BatchGetUserInfoResponse 批量获取用户基本信息响应
+type BatchGetUserInfoResponse struct {
+	UserInfoList []UserInfo `json:"user_info_list"`
 	Error
 }
 
@@ -149,22 +332,600 @@ type GetWebAuthAccessTokenResponse struct {
 	Error
 }
 
+// 素材类型
+const (
+	MediaTypeImage = "image" // 图片
+	MediaTypeVoice = "voice" // 语音
+	MediaTypeVideo = "video" // 视频
+	MediaTypeThumb = "thumb" // 缩略图
+)
+
+// UploadTempMediaResponse 新增临时素材响应
+type UploadTempMediaResponse struct {
+	Type         string `json:"type"`           // 媒体文件类型
+	MediaID      string `json:"media_id"`       // 媒体文件上传后，获取标识
+	ThumbMediaID string `json:"thumb_media_id"` // 缩略图类型的媒体文件标识
+	CreatedAt    int64  `json:"created_at"`     // 媒体文件上传时间戳
+	Error
+}
+
+// UploadArticleImageResponse 上传图文消息内的图片响应
+type UploadArticleImageResponse struct {
+	URL string `json:"url"` // 图片的URL，可放置在图文消息的正文中
+	Error
+}
+
 // AddMediaResponse 新增永久素材响应
 type AddMediaResponse struct {
 	Error
 	MediaId string `json:"media_id"` // 媒体文件上传后，获取标识
+	URL     string `json:"url"`      // 图片素材的URL，仅图片素材返回，只能在腾讯系域名内使用
+}
+
+// VideoDescription 永久视频素材的描述
+type VideoDescription struct {
+	Title        string `json:"title"`        // 视频素材的标题
+	Introduction string `json:"introduction"` // 视频素材的描述
+}
+
+// MaterialNewsItem 永久图文素材中的单篇文章
+type MaterialNewsItem struct {
+	Title              string `json:"title"`                 // 图文消息的标题
+	ThumbMediaID       string `json:"thumb_media_id"`        // 图文消息的封面图片素材id
+	ShowCoverPic       int    `json:"show_cover_pic"`        // 是否显示封面，0为false，1为true
+	Author             string `json:"author"`                // 作者
+	Digest             string `json:"digest"`                // 图文消息的摘要
+	Content            string `json:"content"`               // 图文消息的具体内容
+	URL                string `json:"url"`                   // 图文页的URL
+	ContentSourceURL   string `json:"content_source_url"`    // 图文消息的原文地址
+	NeedOpenComment    int    `json:"need_open_comment"`     // 是否打开评论，0不打开，1打开
+	OnlyFansCanComment int    `json:"only_fans_can_comment"` // 是否粉丝才可评论，0所有人可评论，1粉丝才可评论
+}
+
+// GetMaterialCountResponse 获取素材总数响应
+type GetMaterialCountResponse struct {
+	VoiceCount int `json:"voice_count"` // 语音总数量
+	VideoCount int `json:"video_count"` // 视频总数量
+	ImageCount int `json:"image_count"` // 图片总数量
+	NewsCount  int `json:"news_count"`  // 图文总数量
+	Error
+}
+
+// MaterialItem 素材列表中的单个素材
+type MaterialItem struct {
+	MediaID    string `json:"media_id"`    // 素材id
+	Name       string `json:"name"`        // 文件名称，图文素材不返回
+	UpdateTime int64  `json:"update_time"` // 最后更新时间
+	URL        string `json:"url"`         // 图片素材的URL
+	Content    struct {
+		NewsItem []MaterialNewsItem `json:"news_item"`
+	} `json:"content"` // 图文素材的内容
+}
+
+// BatchGetMaterialResponse 获取素材列表响应
+type BatchGetMaterialResponse struct {
+	TotalCount int            `json:"total_count"` // 该类型的素材的总数
+	ItemCount  int            `json:"item_count"`  // 本次调用获取的素材的数量
+	Item       []MaterialItem `json:"item"`
+	Error
+}
+
+// 图文消息的文章类型
+const (
+	ArticleTypeNews    = "news"    // 图文消息
+	ArticleTypeNewsPic = "newspic" // 图片消息
+)
+
+// Article 草稿及已发布图文中的单篇文章
+type Article struct {
+	ArticleType        string            `json:"article_type,omitempty"`       // 文章类型，news为图文消息（默认），newspic为图片消息
+	Title              string            `json:"title"`                        // 标题
+	Author             string            `json:"author,omitempty"`             // 作者
+	Digest             string            `json:"digest,omitempty"`             // 图文消息的摘要，仅有单图文消息才有摘要，多图文此处为空
+	Content            string            `json:"content"`                      // 图文消息的具体内容，支持HTML标签，图片URL必须来源于上传图文消息内的图片接口
+	ContentSourceURL   string            `json:"content_source_url,omitempty"` // 图文消息的原文地址，即点击“阅读原文”后的URL
+	ThumbMediaID       string            `json:"thumb_media_id,omitempty"`     // 图文消息的封面图片素材id（必须是永久素材），图文消息必填
+	NeedOpenComment    int               `json:"need_open_comment"`            // 是否打开评论，0不打开（默认），1打开
+	OnlyFansCanComment int               `json:"only_fans_can_comment"`        // 是否粉丝才可评论，0所有人可评论（默认），1粉丝才可评论
+	PicCrop2351        string            `json:"pic_crop_235_1,omitempty"`     // 封面裁剪为2.35:1规格的坐标字段，格式为 X1_Y1_X2_Y2，可通过 PicCrop 生成
+	PicCrop11          string            `json:"pic_crop_1_1,omitempty"`       // 封面裁剪为1:1规格的坐标字段，格式为 X1_Y1_X2_Y2，可通过 PicCrop 生成
+	ImageInfo          *ArticleImageInfo `json:"image_info,omitempty"`         // 图片消息里的图片，图片消息必填
+	URL                string            `json:"url,omitempty"`                // 草稿的临时链接，查询时返回
+	ThumbURL           string            `json:"thumb_url,omitempty"`          // 封面图片的URL，查询时返回
+	IsDeleted          bool              `json:"is_deleted,omitempty"`         // 该图文是否被删除，查询已发布图文时返回
+}
+
+// ArticleImageInfo 图片消息里的图片
+type ArticleImageInfo struct {
+	ImageList []struct {
+		ImageMediaID string `json:"image_media_id"` // 图片素材id（必须是永久素材）
+	} `json:"image_list"`
+}
+
+// AddDraftResponse 新建草稿响应
+type AddDraftResponse struct {
+	MediaID string `json:"media_id"` // 上传后的草稿的media_id
+	Error
+}
+
+// GetDraftResponse 获取草稿响应
+type GetDraftResponse struct {
+	NewsItem []Article `json:"news_item"`
+	Error
+}
+
+// GetDraftCountResponse 获取草稿总数响应
+type GetDraftCountResponse struct {
+	TotalCount int `json:"total_count"` // 草稿的总数
+	Error
+}
+
+// DraftItem 草稿列表中的单个草稿
+type DraftItem struct {
+	MediaID string `json:"media_id"` // 草稿的media_id
+	Content struct {
+		NewsItem []Article `json:"news_item"`
+	} `json:"content"`
+	UpdateTime int64 `json:"update_time"` // 草稿的最后更新时间
+}
+
+// BatchGetDraftResponse 获取草稿列表响应
+type BatchGetDraftResponse struct {
+	TotalCount int         `json:"total_count"` // 草稿的总数
+	ItemCount  int         `json:"item_count"`  // 本次调用获取的草稿数量
+	Item       []DraftItem `json:"item"`
+	Error
+}
+
+// 发布状态
+const (
+	PublishStatusSuccess        = 0 // 成功
+	PublishStatusPublishing     = 1 // 发布中
+	PublishStatusOriginalFailed = 2 // 原创失败
+	PublishStatusFailed         = 3 // 常规失败
+	PublishStatusAuditFailed    = 4 // 平台审核不通过
+	PublishStatusUserDeleted    = 5 // 成功后用户删除所有文章
+	PublishStatusBanned         = 6 // 成功后系统封禁所有文章
+)
+
+// PublishEventInfo 发布任务的结果，用于发布任务完成事件推送及发布状态查询
+type PublishEventInfo struct {
+	PublishID     string               `xml:"publish_id" json:"publish_id"`         // 发布任务id
+	PublishStatus int                  `xml:"publish_status" json:"publish_status"` // 发布状态
+	ArticleID     string               `xml:"article_id" json:"article_id"`         // 发布成功时返回的图文的article_id，可用于删除发布
+	ArticleDetail PublishArticleDetail `xml:"article_detail" json:"article_detail"` // 发布成功时返回的文章详情
+	FailIdx       []int                `xml:"fail_idx" json:"fail_idx"`             // 原创审核不通过或常规失败时，失败的文章编号，第一篇为1
+}
+
+// PublishArticleDetail 发布成功的文章
+type PublishArticleDetail struct {
+	Count int `xml:"count" json:"count"` // 文章数量
+	Item  []struct {
+		Idx        int    `xml:"idx" json:"idx"`                 // 文章对应的编号，第一篇为1
+		ArticleURL string `xml:"article_url" json:"article_url"` // 文章的永久链接
+	} `xml:"item" json:"item"`
+}
+
+// SubmitPublishResponse 发布接口响应
+type SubmitPublishResponse struct {
+	PublishID string `json:"publish_id"`  // 发布任务的id
+	MsgDataID int64  `json:"msg_data_id"` // 消息的数据ID
+	Error
+}
+
+// GetPublishStatusResponse 发布状态查询响应
+type GetPublishStatusResponse struct {
+	PublishEventInfo
+	Error
+}
+
+// PublishedItem 已发布图文列表中的单个图文
+type PublishedItem struct {
+	ArticleID string `json:"article_id"` // 成功发布的图文消息id
+	Content   struct {
+		NewsItem []Article `json:"news_item"`
+	} `json:"content"`
+	UpdateTime int64 `json:"update_time"` // 这篇图文消息素材的最后更新时间
+}
+
+// BatchGetPublishedResponse 获取成功发布列表响应
+type BatchGetPublishedResponse struct {
+	TotalCount int             `json:"total_count"` // 成功发布素材的总数
+	ItemCount  int             `json:"item_count"`  // 本次调用获取的素材的数量
+	Item       []PublishedItem `json:"item"`
+	Error
+}
+
+// 评论列表的筛选类型
+const (
+	CommentTypeAll     = 0 // 全部评论
+	CommentTypeNormal  = 1 // 普通评论
+	CommentTypeElected = 2 // 精选评论
+)
+
+// Comment 图文消息的用户评论
+type Comment struct {
+	UserCommentID int64         `json:"user_comment_id"` // 用户评论id
+	OpenID        string        `json:"openid"`          // 评论用户的openid
+	CreateTime    int64         `json:"create_time"`     // 评论时间
+	Content       string        `json:"content"`         // 评论内容
+	CommentType   int           `json:"comment_type"`    // 是否精选评论，0为否，1为是
+	Reply         *CommentReply `json:"reply"`           // 作者回复，没有回复时为nil
+}
+
+// Elected 是否为精选评论
+func (c *Comment) Elected() bool {
+	return c.CommentType == 1
+}
+
+// CommentReply 作者对评论的回复
+type CommentReply struct {
+	Content    string `json:"content"`     // 回复内容
+	CreateTime int64  `json:"create_time"` // 回复时间
+}
+
+// ListCommentResponse 查看指定文章的评论数据响应
+type ListCommentResponse struct {
+	Total   int       `json:"total"`   // 评论总数
+	Comment []Comment `json:"comment"` // 评论列表
+	Error
 }
 
 type Menu struct {
 	Button []MenuButton `json:"button"`
+	MenuID MenuID       `json:"menuid,omitempty"` // 菜单ID，查询菜单时返回
+}
+
+// MenuID 菜单ID，接口中可能以字符串或数字形式返回
+type MenuID string
+
+func (id *MenuID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	*id = MenuID(strings.Trim(string(data), `"`))
+	return nil
+}
+
+// 个性化菜单匹配的客户端版本
+const (
+	ClientPlatformIOS     = "1" // IOS
+	ClientPlatformAndroid = "2" // Android
+	ClientPlatformOthers  = "3" // Others
+)
+
+// MatchRule 个性化菜单匹配规则，至少需要填写一个字段
+type MatchRule struct {
+	TagID              string `json:"tag_id,omitempty"`               // 用户标签的id，可通过用户标签管理接口获取
+	Sex                string `json:"sex,omitempty"`                  // 性别（已废弃）
+	Country            string `json:"country,omitempty"`              // 国家信息（已废弃）
+	Province           string `json:"province,omitempty"`             // 省份信息（已废弃）
+	City               string `json:"city,omitempty"`                 // 城市信息（已废弃）
+	ClientPlatformType string `json:"client_platform_type,omitempty"` // 客户端版本，1 IOS，2 Android，3 Others
+	Language           string `json:"language,omitempty"`             // 语言信息，如 zh_CN、zh_TW、en
+}
+
+// UnmarshalJSON 兼容查询菜单时以数字返回的字段，并将旧版的group_id映射为TagID
+func (r *MatchRule) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		TagID              looseString `json:"tag_id"`
+		GroupID            looseString `json:"group_id"`
+		Sex                looseString `json:"sex"`
+		Country            looseString `json:"country"`
+		Province           looseString `json:"province"`
+		City               looseString `json:"city"`
+		ClientPlatformType looseString `json:"client_platform_type"`
+		Language           looseString `json:"language"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = MatchRule{
+		TagID:              string(raw.TagID),
+		Sex:                string(raw.Sex),
+		Country:            string(raw.Country),
+		Province:           string(raw.Province),
+		City:               string(raw.City),
+		ClientPlatformType: string(raw.ClientPlatformType),
+		Language:           string(raw.Language),
+	}
+	if r.TagID == "" {
+		r.TagID = string(raw.GroupID)
+	}
+	return nil
+}
+
+// looseString 可由字符串或数字解析的字符串
+type looseString string
+
+func (l *looseString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*l = looseString(value)
+		return nil
+	}
+	*l = looseString(data)
+	return nil
+}
+
+// ConditionalMenu 个性化菜单
+type ConditionalMenu struct {
+	Button    []MenuButton `json:"button"`
+	MatchRule MatchRule    `json:"matchrule"`
+	MenuID    MenuID       `json:"menuid,omitempty"` // 菜单ID，创建后由微信分配
+}
+
+// AddConditionalMenuResponse 创建个性化菜单响应
+type AddConditionalMenuResponse struct {
+	MenuID MenuID `json:"menuid"`
+	Error
+}
+
+// TryMatchMenuResponse 测试个性化菜单匹配结果响应
+type TryMatchMenuResponse struct {
+	Menu
+	Error
 }
 
 type MenuButton struct {
-	Type      string       `json:"type,omitempty"`
-	Name      string       `json:"name,omitempty"`
-	Key       string       `json:"key,omitempty"`
-	Url       string       `json:"url,omitempty"`
-	AppID     string       `json:"appid,omitempty"`
-	PagePath  string       `json:"pagepath,omitempty"`
-	SubButton []MenuButton `json:"sub_button,omitempty"`
+	Type      string        `json:"type,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	Key       string        `json:"key,omitempty"`
+	Url       string        `json:"url,omitempty"`
+	AppID     string        `json:"appid,omitempty"`
+	PagePath  string        `json:"pagepath,omitempty"`
+	MediaID   string        `json:"media_id,omitempty"`   // media_id、view_limited 类型按钮的永久素材id
+	ArticleID string        `json:"article_id,omitempty"` // article_id、article_view_limited 类型按钮的发布后文章id
+	Value     string        `json:"value,omitempty"`      // 公众平台官网配置的菜单中，text、img、voice、video 类型按钮的内容
+	NewsInfo  *MenuNewsInfo `json:"news_info,omitempty"`  // 公众平台官网配置的菜单中，news 类型按钮的图文消息
+	SubButton []MenuButton  `json:"sub_button,omitempty"`
+}
+
+// UnmarshalJSON 兼容公众平台官网配置的菜单中 sub_button 为 {"list": [...]} 的格式
+func (b *MenuButton) UnmarshalJSON(data []byte) error {
+	type plain MenuButton
+	var raw struct {
+		plain
+		SubButton json.RawMessage `json:"sub_button"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = MenuButton(raw.plain)
+	b.SubButton = nil
+
+	sub := bytes.TrimSpace(raw.SubButton)
+	switch {
+	case len(sub) == 0 || bytes.Equal(sub, []byte("null")):
+		return nil
+	case sub[0] == '{':
+		var wrapped struct {
+			List []MenuButton `json:"list"`
+		}
+		if err := json.Unmarshal(sub, &wrapped); err != nil {
+			return err
+		}
+		b.SubButton = wrapped.List
+		return nil
+	default:
+		return json.Unmarshal(sub, &b.SubButton)
+	}
+}
+
+// MenuNewsInfo 菜单中的图文消息
+type MenuNewsInfo struct {
+	List []MenuNewsItem `json:"list"`
+}
+
+// MenuNewsItem 菜单图文消息中的单篇文章
+type MenuNewsItem struct {
+	Title      string `json:"title"`       // 图文消息的标题
+	Author     string `json:"author"`      // 作者
+	Digest     string `json:"digest"`      // 摘要
+	ShowCover  int    `json:"show_cover"`  // 是否显示封面，0为不显示，1为显示
+	CoverURL   string `json:"cover_url"`   // 封面图片的URL
+	ContentURL string `json:"content_url"` // 正文的URL
+	SourceURL  string `json:"source_url"`  // 原文的URL，若置空则无查看原文入口
+}
+
+// GetMenuResponse 查询自定义菜单响应（仅能查询到使用API设置的菜单）
+type GetMenuResponse struct {
+	Menu            Menu              `json:"menu"`            // 默认菜单
+	ConditionalMenu []ConditionalMenu `json:"conditionalmenu"` // 个性化菜单
+	Error
+}
+
+// GetCurrentSelfMenuInfoResponse 获取当前使用的自定义菜单配置响应
+type GetCurrentSelfMenuInfoResponse struct {
+	IsMenuOpen   int  `json:"is_menu_open"`  // 菜单是否开启，0代表未开启，1代表开启
+	SelfMenuInfo Menu `json:"selfmenu_info"` // 菜单信息
+	Error
+}
+
+// SubscribeCategory 公众号类目
+type SubscribeCategory struct {
+	ID   int    `json:"id"`   // 类目id，查询公共模版库时需要
+	Name string `json:"name"` // 类目的中文名
+}
+
+// GetSubscribeCategoryResponse 获取公众号类目响应
+type GetSubscribeCategoryResponse struct {
+	Data []SubscribeCategory `json:"data"`
+	Error
+}
+
+// PubTemplateTitle 公共模版标题
+type PubTemplateTitle struct {
+	Tid        int    `json:"tid"`        // 模版标题id
+	Title      string `json:"title"`      // 模版标题
+	Type       int    `json:"type"`       // 模版类型，2 为一次性订阅，3 为长期订阅
+	CategoryID string `json:"categoryId"` // 模版所属类目id
+}
+
+// GetPubTemplateTitlesResponse 获取类目下的公共模版响应
+type GetPubTemplateTitlesResponse struct {
+	Count int                `json:"count"` // 模版标题列表总数
+	Data  []PubTemplateTitle `json:"data"`
+	Error
+}
+
+// PubTemplateKeyword 公共模版关键词
+type PubTemplateKeyword struct {
+	Kid     int    `json:"kid"`     // 关键词id，选用模版时需要
+	Name    string `json:"name"`    // 关键词内容
+	Example string `json:"example"` // 关键词内容对应的示例
+	Rule    string `json:"rule"`    // 参数类型
+}
+
+// GetPubTemplateKeywordsResponse 获取模版中的关键词响应
+type GetPubTemplateKeywordsResponse struct {
+	Count int                  `json:"count"` // 关键词总数
+	Data  []PubTemplateKeyword `json:"data"`
+	Error
+}
+
+// AddSubscribeTemplateResponse 选用模版响应
+type AddSubscribeTemplateResponse struct {
+	PriTmplID string `json:"priTmplId"` // 添加至账号下的模版id，发送订阅通知时所需
+	Error
+}
+
+// SubscribeTemplate 账号下的订阅通知模版
+type SubscribeTemplate struct {
+	PriTmplID string `json:"priTmplId"` // 添加至账号下的模版id
+	Title     string `json:"title"`     // 模版标题
+	Content   string `json:"content"`   // 模版内容
+	Example   string `json:"example"`   // 模版内容示例
+	Type      int    `json:"type"`      // 模版类型，2 为一次性订阅，3 为长期订阅
+}
+
+// GetSubscribeTemplatesResponse 获取私有模版列表响应
+type GetSubscribeTemplatesResponse struct {
+	Data []SubscribeTemplate `json:"data"`
+	Error
+}
+
+// SubscribeMessage 订阅通知
+type SubscribeMessage struct {
+	ToUser      string                          `json:"touser"`                // 接收者openid
+	TemplateID  string                          `json:"template_id"`           // 所需下发的订阅模版id
+	Page        string                          `json:"page,omitempty"`        // 跳转网页时填写
+	MiniProgram *SubscribeMessageMiniProgram    `json:"miniprogram,omitempty"` // 跳转小程序时填写
+	Data        map[string]SubscribeMessageData `json:"data"`                  // 模版内容，格式形如 { "key1": { "value": any }, "key2": { "value": any } }
+}
+type SubscribeMessageData struct {
+	Value string `json:"value"`
+}
+type SubscribeMessageMiniProgram struct {
+	AppID    string `json:"appid"`    // 所需跳转到的小程序appid
+	PagePath string `json:"pagepath"` // 所需跳转到小程序的具体页面路径
+}
+
+// OneTimeSubscribeMessage 一次性订阅消息
+type OneTimeSubscribeMessage struct {
+	ToUser      string                       `json:"touser"`                // 接收者openid，需已授权
+	TemplateID  string                       `json:"template_id"`           // 订阅消息模版ID
+	URL         string                       `json:"url,omitempty"`         // 点击消息跳转的链接，需要有ICP备案
+	MiniProgram *SubscribeMessageMiniProgram `json:"miniprogram,omitempty"` // 跳小程序所需数据，不需跳小程序可不用传该数据
+	Scene       int                          `json:"scene,string"`          // 订阅场景值，与授权时的scene一致
+	Title       string                       `json:"title"`                 // 消息标题，15字以内
+	Data        OneTimeSubscribeMessageData  `json:"data"`                  // 消息正文
+}
+type OneTimeSubscribeMessageData struct {
+	Content struct {
+		Value string `json:"value"`           // 消息正文，200字以内
+		Color string `json:"color,omitempty"` // 文字颜色
+	} `json:"content"`
+}
+
+// MassSendResponse 群发消息响应
+type MassSendResponse struct {
+	MsgID     int64 `json:"msg_id"`      // 消息发送任务的ID
+	MsgDataID int64 `json:"msg_data_id"` // 消息的数据ID，仅在群发图文消息时返回
+	Error
+}
+
+// GetMassStatusResponse 查询群发消息发送状态响应
+type GetMassStatusResponse struct {
+	MsgID     int64  `json:"msg_id"`     // 群发消息后返回的消息id
+	MsgStatus string `json:"msg_status"` // 消息发送后的状态，SEND_SUCCESS 发送成功，SENDING 发送中，SEND_FAIL 发送失败，DELETE 已删除
+	Error
+}
+
+// MassSpeed 群发速度
+type MassSpeed struct {
+	Speed     int `json:"speed"`     // 群发速度的级别，0-4，0最快
+	RealSpeed int `json:"realspeed"` // 群发速度的真实值，单位：万/分钟
+	Error
+}
+
+// Tag 用户标签
+type Tag struct {
+	ID    int    `json:"id,omitempty"`    // 标签id，由微信分配
+	Name  string `json:"name,omitempty"`  // 标签名，UTF8编码，30个字符以内
+	Count int    `json:"count,omitempty"` // 此标签下粉丝数
+}
+
+// TagResponse 创建标签响应
+type TagResponse struct {
+	Tag Tag `json:"tag"`
+	Error
+}
+
+// GetTagsResponse 获取公众号已创建的标签响应
+type GetTagsResponse struct {
+	Tags []Tag `json:"tags"`
+	Error
+}
+
+// GetUserTagIDsResponse 获取用户身上的标签列表响应
+type GetUserTagIDsResponse struct {
+	TagIDList []int `json:"tagid_list"` // 被置上的标签列表
+	Error
+}
+
+// GetTagUsersResponse 获取标签下粉丝列表响应
+type GetTagUsersResponse struct {
+	Count int `json:"count"` // 这次获取的粉丝数量
+	Data  struct {
+		OpenID []string `json:"openid"`
+	} `json:"data"` // 粉丝列表
+	NextOpenID string `json:"next_openid"` // 拉取列表最后一个用户的openid
+	Error
+}
+
+// GetBlacklistResponse 获取公众号的黑名单列表响应
+type GetBlacklistResponse struct {
+	Total int `json:"total"` // 黑名单用户总数
+	Count int `json:"count"` // 这次获取的用户数量
+	Data  struct {
+		OpenID []string `json:"openid"`
+	} `json:"data"` // 黑名单列表
+	NextOpenID string `json:"next_openid"` // 拉取列表最后一个用户的openid
+	Error
+}
+
+// OpenIDResult 批量操作中单个用户的结果
+type OpenIDResult struct {
+	OpenID string
+	Err    error // 操作失败的原因，成功时为nil
+}
+
+// ChangeOpenIDResult 单个openid的转换结果
+type ChangeOpenIDResult struct {
+	OriOpenID string `json:"ori_openid"` // 原账号的openid
+	NewOpenID string `json:"new_openid"` // 新账号的openid，转换失败时为空
+	ErrMsg    string `json:"err_msg"`    // 转换失败的原因，如 ori_openid error
+}
+
+// ChangeOpenIDResponse openid转换响应
+type ChangeOpenIDResponse struct {
+	ResultList []ChangeOpenIDResult `json:"result_list"`
+	Error
 }
@@ -0,0 +1,317 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// batchGetUserInfoLimit 批量获取用户基本信息时单次请求的openid数量上限
+const batchGetUserInfoLimit = 100
+
+// BatchGetUserInfo 批量获取用户基本信息，超过100个openid时自动分批请求，lang为空时使用简体中文
+// 开启用户缓存时只请求未命中缓存的用户，返回结果按openIDs的顺序排列
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Get_users_basic_information_UnionID.html#UinonId
+func (s *SDK) BatchGetUserInfo(openIDs []string, lang string) ([]UserInfo, error) {
+	if lang == "" {
+		lang = LangZhCN
+	}
+	cache := s.cache()
+	if cache == nil {
+		return s.batchGetUserInfo(openIDs, lang)
+	}
+
+	cached := make(map[string]UserInfo)
+	var missing []string
+	for _, openID := range openIDs {
+		if info, ok := cache.get(openID, lang); ok {
+			cached[openID] = info
+		} else {
+			missing = append(missing, openID)
+		}
+	}
+	fetched, err := s.batchGetUserInfo(missing, lang)
+	for _, info := range fetched {
+		cache.set(info, lang)
+		cached[info.OpenID] = info
+	}
+
+	list := make([]UserInfo, 0, len(openIDs))
+	for _, openID := range openIDs {
+		if info, ok := cached[openID]; ok {
+			list = append(list, info)
+		}
+	}
+	return list, err
+}
+
+func (s *SDK) batchGetUserInfo(openIDs []string, lang string) ([]UserInfo, error) {
+	list := make([]UserInfo, 0, len(openIDs))
+	for _, chunk := range chunkOpenIDs(openIDs, batchGetUserInfoLimit) {
+		if err := s.checkAccessToken(); err != nil {
+			return list, err
+		}
+		userList := make([]map[string]string, 0, len(chunk))
+		for _, openID := range chunk {
+			userList = append(userList, map[string]string{"openid": openID, "lang": lang})
+		}
+		url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/user/info/batchget?access_token=%s", s.AccessToken)
+
+		var responseJson BatchGetUserInfoResponse
+		if err := postJSON(url, map[string]interface{}{"user_list": userList}, &responseJson); err != nil {
+			return list, err
+		}
+		if responseJson.Errcode != 0 {
+			return list, ErrorHandler(ErrBatchGetUserInfo, responseJson.Errmsg, responseJson.Errcode)
+		}
+		list = append(list, responseJson.UserInfoList...)
+	}
+	return list, nil
+}
+
+// FollowerInfoOptions 遍历关注者基本信息的选项
+type FollowerInfoOptions struct {
+	Concurrency int    // 同时进行的批量请求数，默认为4
+	Lang        string // 返回信息的语言，默认为简体中文
+	StartOpenID string // 起始openid（不包含），为空时从头开始
+}
+
+// ForEachFollowerInfo 遍历全部关注者并批量获取其基本信息，对每个用户调用fn
+// 关注者列表按页拉取，每100个openid通过批量接口并发获取信息，内存中只保留少量批次
+// fn 会被多个协程并发调用；fn返回错误或ctx被取消时停止遍历并返回该错误
+func (s *SDK) ForEachFollowerInfo(ctx context.Context, options FollowerInfoOptions, fn func(info *UserInfo) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		firstErr error
+		errOnce  sync.Once
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	batches := make(chan []string, options.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				// ctx被取消后不再处理剩余批次，由调用方返回ctx的错误
+				if ctx.Err() != nil {
+					return
+				}
+				list, err := s.BatchGetUserInfo(batch, options.Lang)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				for i := range list {
+					if err = fn(&list[i]); err != nil {
+						fail(err)
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	// 按页拉取关注者，凑满100个openid提交一个批次
+	it := s.Followers(ctx, options.StartOpenID)
+	batch := make([]string, 0, batchGetUserInfoLimit)
+	for it.Next() {
+		batch = append(batch, it.OpenID())
+		if len(batch) == batchGetUserInfoLimit {
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+			}
+			batch = make([]string, 0, batchGetUserInfoLimit)
+		}
+	}
+	if len(batch) > 0 {
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// 关注者已全部拉取但仍有批次未处理时，同样需要返回取消原因
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+// blacklistLimit 批量拉黑、取消拉黑时单次请求的openid数量上限
+const blacklistLimit = 20
+
+// UpdateUserRemark 设置用户备注名，remark长度必须小于30字符
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Configuring_user_notes.html
+func (s *SDK) UpdateUserRemark(openID, remark string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"openid": openID,
+		"remark": remark,
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/user/info/updateremark?access_token=%s", s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, data, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(ErrUpdateUserRemark, responseJson.Errmsg, responseJson.Errcode)
+	}
+	s.invalidateUsers(openID)
+	return nil
+}
+
+// GetBlacklist 获取公众号的黑名单列表，beginOpenID为空时从头开始拉取，每次最多拉取10000个
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Manage_blacklist.html
+func (s *SDK) GetBlacklist(beginOpenID string) (*GetBlacklistResponse, error) {
+	if err := s.checkAccessToken(); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/members/getblacklist?access_token=%s", s.AccessToken)
+
+	var responseJson GetBlacklistResponse
+	if err := postJSON(url, map[string]interface{}{"begin_openid": beginOpenID}, &responseJson); err != nil {
+		return nil, err
+	}
+	if responseJson.Errcode != 0 {
+		return nil, ErrorHandler(ErrGetBlacklist, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return &responseJson, nil
+}
+
+// Blacklist 返回全部黑名单用户的迭代器，自动处理分页，startOpenID为空时从头开始
+func (s *SDK) Blacklist(ctx context.Context, startOpenID string) *OpenIDIterator {
+	return newOpenIDIterator(ctx, startOpenID, func(nextOpenID string) ([]string, string, error) {
+		resp, err := s.GetBlacklist(nextOpenID)
+		if err != nil {
+			return nil, "", err
+		}
+		if resp.Count == 0 {
+			return nil, "", nil
+		}
+		return resp.Data.OpenID, resp.NextOpenID, nil
+	})
+}
+
+// BatchBlacklist 拉黑用户，超过20个openid时自动分批请求，返回每个用户的结果
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Manage_blacklist.html
+func (s *SDK) BatchBlacklist(openIDs []string) []OpenIDResult {
+	return s.batchBlacklist("batchblacklist", ErrBatchBlacklist, openIDs)
+}
+
+// BatchUnblacklist 取消拉黑用户，超过20个openid时自动分批请求，返回每个用户的结果
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Manage_blacklist.html
+func (s *SDK) BatchUnblacklist(openIDs []string) []OpenIDResult {
+	return s.batchBlacklist("batchunblacklist", ErrBatchUnblacklist, openIDs)
+}
+
+// blacklistOpenIDErrcodes 由批次中个别openid引起的错误码，40003 非法的openid，49003 openid不属于此AppID
+var blacklistOpenIDErrcodes = map[int]bool{40003: true, 49003: true}
+
+// isBlacklistOpenIDError 判断错误是否由个别openid引起
+func isBlacklistOpenIDError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && blacklistOpenIDErrcodes[apiErr.Errcode]
+}
+
+// batchBlacklist 分批拉黑或取消拉黑，批次因个别openid失败时逐个重试以确定每个用户的结果
+// 其他错误（如调用频率超限、access_token失效或网络错误）重试也会失败，当前及之后的用户直接返回该错误
+func (s *SDK) batchBlacklist(action, errAction string, openIDs []string) []OpenIDResult {
+	results := make([]OpenIDResult, 0, len(openIDs))
+	fail := func(err error) []OpenIDResult {
+		for _, openID := range openIDs[len(results):] {
+			results = append(results, OpenIDResult{OpenID: openID, Err: err})
+		}
+		return results
+	}
+	for _, chunk := range chunkOpenIDs(openIDs, blacklistLimit) {
+		err := s.postBlacklist(action, errAction, chunk)
+		if err != nil && !isBlacklistOpenIDError(err) {
+			return fail(err)
+		}
+		if err == nil || len(chunk) == 1 {
+			for _, openID := range chunk {
+				results = append(results, OpenIDResult{OpenID: openID, Err: err})
+			}
+			continue
+		}
+		for _, openID := range chunk {
+			err = s.postBlacklist(action, errAction, []string{openID})
+			if err != nil && !isBlacklistOpenIDError(err) {
+				return fail(err)
+			}
+			results = append(results, OpenIDResult{OpenID: openID, Err: err})
+		}
+	}
+	return results
+}
+
+func (s *SDK) postBlacklist(action, errAction string, openIDs []string) error {
+	if err := s.checkAccessToken(); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/tags/members/%s?access_token=%s", action, s.AccessToken)
+
+	var responseJson Error
+	if err := postJSON(url, map[string]interface{}{"openid_list": openIDs}, &responseJson); err != nil {
+		return err
+	}
+	if responseJson.Errcode != 0 {
+		return ErrorHandler(errAction, responseJson.Errmsg, responseJson.Errcode)
+	}
+	return nil
+}
+
+// changeOpenIDLimit openid转换时单次请求的openid数量上限
+const changeOpenIDLimit = 100
+
+// ChangeOpenID 将原账号的openid转换为当前账号的openid，用于公众号迁移，超过100个openid时自动分批请求
+// 需在迁移审核完成后15天内调用，单个openid转换失败时结果中的ErrMsg不为空
+// 官方文档地址 https://developers.weixin.qq.com/doc/offiaccount/User_Management/Get_users_basic_information_UnionID.html#%E5%85%AC%E4%BC%97%E5%8F%B7%E8%BF%81%E7%A7%BB%E5%90%8Eopenid%E8%BD%AC%E6%8D%A2
+func (s *SDK) ChangeOpenID(fromAppID string, openIDs []string) ([]ChangeOpenIDResult, error) {
+	results := make([]ChangeOpenIDResult, 0, len(openIDs))
+	for _, chunk := range chunkOpenIDs(openIDs, changeOpenIDLimit) {
+		if err := s.checkAccessToken(); err != nil {
+			return results, err
+		}
+		data := map[string]interface{}{
+			"from_appid":  fromAppID,
+			"openid_list": chunk,
+		}
+		url := fmt.Sprintf("https://api.weixin.qq.com/cgi-bin/changeopenid?access_token=%s", s.AccessToken)
+
+		var responseJson ChangeOpenIDResponse
+		if err := postJSON(url, data, &responseJson); err != nil {
+			return results, err
+		}
+		if responseJson.Errcode != 0 {
+			return results, ErrorHandler(ErrChangeOpenID, responseJson.Errmsg, responseJson.Errcode)
+		}
+		results = append(results, responseJson.ResultList...)
+	}
+	return results, nil
+}
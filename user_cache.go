@@ -0,0 +1,136 @@
+package wechat
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// UserCache 用户基本信息缓存，按TTL过期，超出容量时淘汰最久未使用的用户
+type UserCache struct {
+	ttl     time.Duration
+	maxSize int
+
+	mutex sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List // 头部为最近使用
+}
+
+type userCacheEntry struct {
+	info      UserInfo
+	lang      string
+	expiresAt time.Time
+}
+
+// newUserCache 实例化用户缓存，maxSize小于等于0时不限制容量
+func newUserCache(ttl time.Duration, maxSize int) *UserCache {
+	return &UserCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get 读取缓存，语言不一致或已过期时视为未命中
+func (c *UserCache) get(openID, lang string) (UserInfo, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.items[openID]
+	if !ok {
+		return UserInfo{}, false
+	}
+	entry := element.Value.(*userCacheEntry)
+	if entry.lang != lang || time.Now().After(entry.expiresAt) {
+		return UserInfo{}, false
+	}
+	c.lru.MoveToFront(element)
+	return entry.info, true
+}
+
+// set 写入缓存
+func (c *UserCache) set(info UserInfo, lang string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := &userCacheEntry{info: info, lang: lang, expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.items[info.OpenID]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.items[info.OpenID] = c.lru.PushFront(entry)
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*userCacheEntry).info.OpenID)
+	}
+}
+
+// Invalidate 删除指定用户的缓存
+func (c *UserCache) Invalidate(openIDs ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, openID := range openIDs {
+		if element, ok := c.items[openID]; ok {
+			c.lru.Remove(element)
+			delete(c.items, openID)
+		}
+	}
+}
+
+// Purge 清空缓存
+func (c *UserCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len 返回当前缓存的用户数
+func (c *UserCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+// EnableUserCache 为 GetUserInfo 和 BatchGetUserInfo 开启用户信息缓存
+// ttl为缓存有效期，maxSize为最多缓存的用户数（小于等于0时不限制）
+// 用户关注、取消关注，以及通过SDK修改用户标签、备注后，对应缓存会自动失效
+func (s *SDK) EnableUserCache(ttl time.Duration, maxSize int) *UserCache {
+	cache := newUserCache(ttl, maxSize)
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+	s.userCache = cache
+	return cache
+}
+
+// cache 返回当前的用户缓存，未开启时返回nil
+func (s *SDK) cache() *UserCache {
+	s.cacheMutex.RLock()
+	defer s.cacheMutex.RUnlock()
+	return s.userCache
+}
+
+// invalidateUsers 使指定用户的缓存失效
+func (s *SDK) invalidateUsers(openIDs ...string) {
+	if cache := s.cache(); cache != nil {
+		cache.Invalidate(openIDs...)
+	}
+}
+
+// User 获取消息发送方的用户基本信息，首次调用时请求接口，开启用户缓存时优先读取缓存
+func (m *Message) User() (*UserInfo, error) {
+	if m.user != nil {
+		return m.user, nil
+	}
+	if m.sdk == nil {
+		return nil, errors.New("消息未关联SDK，无法获取用户信息")
+	}
+	resp, err := m.sdk.GetUserInfo(m.FromUserName)
+	if err != nil {
+		return nil, err
+	}
+	m.user = &resp.UserInfo
+	return m.user, nil
+}
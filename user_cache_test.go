@@ -0,0 +1,94 @@
+package wechat
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserCache(t *testing.T) {
+	cache := newUserCache(50*time.Millisecond, 2)
+	cache.set(UserInfo{OpenID: "a", Remark: "A"}, LangZhCN)
+	cache.set(UserInfo{OpenID: "b"}, LangZhCN)
+
+	if info, ok := cache.get("a", LangZhCN); !ok || info.Remark != "A" {
+		t.Fatalf("get(a) = %+v, %v", info, ok)
+	}
+	if _, ok := cache.get("a", LangEn); ok {
+		t.Fatal("get() with another lang hit the cache")
+	}
+	// 超出容量时淘汰最久未使用的b
+	cache.set(UserInfo{OpenID: "c"}, LangZhCN)
+	if _, ok := cache.get("b", LangZhCN); ok || cache.Len() != 2 {
+		t.Fatalf("b was not evicted, Len() = %d", cache.Len())
+	}
+	cache.Invalidate("a")
+	if _, ok := cache.get("a", LangZhCN); ok {
+		t.Fatal("get() hit after Invalidate")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.get("c", LangZhCN); ok {
+		t.Fatal("get() hit after ttl")
+	}
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Fatalf("Len() = %d after Purge", cache.Len())
+	}
+}
+
+func TestSDKUserCache(t *testing.T) {
+	var requests []string
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/user/info":
+			requests = append(requests, r.URL.Query().Get("openid"))
+			json.NewEncoder(w).Encode(UserInfo{Subscribe: 1, OpenID: r.URL.Query().Get("openid")})
+		case "/cgi-bin/user/info/batchget":
+			var body struct {
+				UserList []struct {
+					OpenID string `json:"openid"`
+				} `json:"user_list"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			var resp BatchGetUserInfoResponse
+			for _, user := range body.UserList {
+				requests = append(requests, user.OpenID)
+				resp.UserInfoList = append(resp.UserInfoList, UserInfo{Subscribe: 1, OpenID: user.OpenID})
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	})
+	sdk.EnableUserCache(time.Hour, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := sdk.GetUserInfo("a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := sdk.BatchGetUserInfo([]string{"a", "b"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].OpenID != "a" || list[1].OpenID != "b" {
+		t.Fatalf("BatchGetUserInfo() = %+v", list)
+	}
+	// 修改备注后缓存失效
+	if err = sdk.UpdateUserRemark("a", "备注"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sdk.GetUserInfo("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sdk.GetUserInfo("b"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "a"}; !reflect.DeepEqual(requests, want) {
+		t.Fatalf("requested %v, want %v", requests, want)
+	}
+}
@@ -0,0 +1,210 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// followerServer 模拟拉取关注者列表及批量获取用户信息的接口，关注者分为两页
+func followerServer(t *testing.T, total int) http.HandlerFunc {
+	openIDs := make([]string, total)
+	for i := range openIDs {
+		openIDs[i] = fmt.Sprintf("openid-%03d", i)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/user/get":
+			var page []string
+			switch r.URL.Query().Get("next_openid") {
+			case "":
+				page = openIDs[:total/2]
+			case openIDs[total/2-1]:
+				page = openIDs[total/2:]
+			}
+			resp := GetUserListResponse{Total: total, Count: len(page)}
+			if len(page) > 0 {
+				resp.Data.OpenID, resp.NextOpenID = page, page[len(page)-1]
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/cgi-bin/user/info/batchget":
+			var request struct {
+				UserList []struct {
+					OpenID string `json:"openid"`
+				} `json:"user_list"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			if len(request.UserList) > batchGetUserInfoLimit {
+				t.Errorf("batch of %d openids", len(request.UserList))
+			}
+			var resp BatchGetUserInfoResponse
+			for _, user := range request.UserList {
+				resp.UserInfoList = append(resp.UserInfoList, UserInfo{Subscribe: 1, OpenID: user.OpenID})
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}
+}
+
+func TestForEachFollowerInfo(t *testing.T) {
+	sdk := newTestSDK(t, followerServer(t, 250))
+	var (
+		mutex sync.Mutex
+		seen  = make(map[string]int)
+	)
+	err := sdk.ForEachFollowerInfo(context.Background(), FollowerInfoOptions{Concurrency: 3}, func(info *UserInfo) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		seen[info.OpenID]++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 250 {
+		t.Fatalf("visited %d followers, want 250", len(seen))
+	}
+	for openID, count := range seen {
+		if count != 1 {
+			t.Fatalf("%s visited %d times", openID, count)
+		}
+	}
+}
+
+func TestForEachFollowerInfoCanceled(t *testing.T) {
+	sdk := newTestSDK(t, followerServer(t, 250))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var visited int32
+	err := sdk.ForEachFollowerInfo(ctx, FollowerInfoOptions{Concurrency: 1}, func(info *UserInfo) error {
+		if atomic.AddInt32(&visited, 1) == 1 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ForEachFollowerInfo() error = %v, want context.Canceled", err)
+	}
+	if visited >= 250 {
+		t.Fatal("all followers were visited after cancel")
+	}
+}
+
+func TestForEachFollowerInfoCallbackError(t *testing.T) {
+	sdk := newTestSDK(t, followerServer(t, 250))
+	stop := errors.New("stop")
+	err := sdk.ForEachFollowerInfo(context.Background(), FollowerInfoOptions{}, func(info *UserInfo) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("ForEachFollowerInfo() error = %v, want %v", err, stop)
+	}
+}
+
+func TestBatchBlacklist(t *testing.T) {
+	openIDs := openIDList(25)
+	var sizes []int
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OpenIDList []string `json:"openid_list"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		sizes = append(sizes, len(body.OpenIDList))
+		for _, openID := range body.OpenIDList {
+			if openID == openIDs[3] {
+				w.Write([]byte(`{"errcode":49003,"errmsg":"not subscribed"}`))
+				return
+			}
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	})
+
+	results := sdk.BatchBlacklist(openIDs)
+	if len(results) != len(openIDs) {
+		t.Fatalf("BatchBlacklist() returned %d results", len(results))
+	}
+	for i, result := range results {
+		var apiErr *APIError
+		failed := errors.As(result.Err, &apiErr) && apiErr.Errcode == 49003
+		if result.OpenID != openIDs[i] || failed != (i == 3) || (!failed && result.Err != nil) {
+			t.Fatalf("results[%d] = %+v", i, result)
+		}
+	}
+	// 第一批失败后逐个重试，第二批直接成功
+	if len(sizes) != 22 || sizes[0] != 20 || sizes[1] != 1 || sizes[21] != 5 {
+		t.Fatalf("request sizes = %v", sizes)
+	}
+}
+
+func TestBatchBlacklistQuotaError(t *testing.T) {
+	openIDs := openIDList(45)
+	var sizes []int
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OpenIDList []string `json:"openid_list"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		sizes = append(sizes, len(body.OpenIDList))
+		if len(sizes) == 1 {
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+			return
+		}
+		w.Write([]byte(`{"errcode":45009,"errmsg":"reach max api daily quota limit"}`))
+	})
+
+	// 第二批因调用次数超限失败，不逐个重试，之后的批次也不再请求
+	results := sdk.BatchUnblacklist(openIDs)
+	if len(results) != len(openIDs) {
+		t.Fatalf("BatchUnblacklist() returned %d results", len(results))
+	}
+	for i, result := range results {
+		var apiErr *APIError
+		failed := errors.As(result.Err, &apiErr) && apiErr.Errcode == 45009
+		if result.OpenID != openIDs[i] || failed != (i >= 20) || (!failed && result.Err != nil) {
+			t.Fatalf("results[%d] = %+v", i, result)
+		}
+	}
+	if !reflect.DeepEqual(sizes, []int{20, 20}) {
+		t.Fatalf("request sizes = %v", sizes)
+	}
+}
+
+func TestBlacklist(t *testing.T) {
+	openIDs := openIDList(3)
+	sdk := newTestSDK(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			BeginOpenID string `json:"begin_openid"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		// 最后一页之后返回的next_openid为空且数量为0
+		var resp GetBlacklistResponse
+		if body.BeginOpenID == "" {
+			resp.Total, resp.Count, resp.NextOpenID = 3, 3, openIDs[2]
+			resp.Data.OpenID = openIDs
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	it := sdk.Blacklist(context.Background(), "")
+	var got []string
+	for it.Next() {
+		got = append(got, it.OpenID())
+	}
+	if it.Err() != nil || len(got) != 3 || got[2] != openIDs[2] {
+		t.Fatalf("Blacklist() = %v, %v", got, it.Err())
+	}
+}